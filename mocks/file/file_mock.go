@@ -34,6 +34,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	file "github.com/amirvalhalla/fspool/pkg/file"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -366,6 +367,49 @@ func (mr *MockFileHelperMockRecorder) MkdirAll(path, perm interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MkdirAll", reflect.TypeOf((*MockFileHelper)(nil).MkdirAll), path, perm)
 }
 
+// OpenFile mocks base method.
+func (m *MockFileHelper) OpenFile(name string, flag int, perm os.FileMode) (file.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenFile", name, flag, perm)
+	ret0, _ := ret[0].(file.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenFile indicates an expected call of OpenFile.
+func (mr *MockFileHelperMockRecorder) OpenFile(name, flag, perm interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenFile", reflect.TypeOf((*MockFileHelper)(nil).OpenFile), name, flag, perm)
+}
+
+// Remove mocks base method.
+func (m *MockFileHelper) Remove(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockFileHelperMockRecorder) Remove(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockFileHelper)(nil).Remove), path)
+}
+
+// Rename mocks base method.
+func (m *MockFileHelper) Rename(oldPath, newPath string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rename", oldPath, newPath)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rename indicates an expected call of Rename.
+func (mr *MockFileHelperMockRecorder) Rename(oldPath, newPath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rename", reflect.TypeOf((*MockFileHelper)(nil).Rename), oldPath, newPath)
+}
+
 // Stat mocks base method.
 func (m *MockFileHelper) Stat(path string) (os.FileInfo, error) {
 	m.ctrl.T.Helper()
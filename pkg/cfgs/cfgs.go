@@ -1,8 +1,12 @@
 // Package cfgs contains configurations of filesystem , filesystem pool and all others configurations
 package cfgs
 
+import "time"
+
 type FSPerm uint8
 type FlushType uint8
+type IOMode uint8
+type EvictionPolicy uint8
 
 const (
 	ROnly FSPerm = 0
@@ -11,4 +15,56 @@ const (
 
 	FlushBySize FlushType = 0
 	FlushByTime FlushType = 1
+
+	// PositionalIO reads and writes by ReadAt & WriteAt (pread & pwrite), so file offset is never shared between callers
+	PositionalIO IOMode = 0
+	// SeekIO reads and writes by Seek followed by Read & Write, so callers of the same file are serialized
+	SeekIO IOMode = 1
+
+	// EvictNone closes instance of fspool as soon as it's released by all of its holders
+	EvictNone EvictionPolicy = 0
+	// EvictLRU keeps released instances open and closes the least recently used one when fspool reaches its limit
+	EvictLRU EvictionPolicy = 1
+	// EvictLFU keeps released instances open and closes the least frequently used one when fspool reaches its limit
+	EvictLFU EvictionPolicy = 2
 )
+
+/*
+* FlushPolicy defines when buffered data of memory rent should be flushed into file, the first reached condition triggers flushing
+* Size: flushing when buffered data reaches this size (unit is byte)
+* Age: flushing when buffered data gets older than this duration
+* Writes: flushing after this number of writes
+* Tip: zero value of each condition means it's disabled, also you can flush explicitly by Flush func of filesystem as a barrier
+ */
+type FlushPolicy struct {
+	Size   uint64
+	Age    time.Duration
+	Writes uint64
+}
+
+// IsZero reports whether none of conditions of FlushPolicy has been defined
+func (p FlushPolicy) IsZero() bool {
+	return p == FlushPolicy{}
+}
+
+/*
+* Hooks are callbacks of lifecycle and I/O events of filesystem instances and fspool, nil callbacks are skipped
+* OnOpen: filesystem instance of file has been opened
+* OnClose: filesystem instance of file has been closed (err is nil if closing has succeeded)
+* OnEvict: idle filesystem instance has been closed by fspool, reason is limit, idle timeout or max lifetime
+* OnFlush: memory rent has been flushed into file (bytes is size of flushed data)
+* OnWrite: Write has been called (bytes is size of raw data)
+* OnRead: ReadData or ReadAllData has been called (bytes is size of read data)
+* OnError: an operation of filesystem instance has failed, op is kind of operation (open, write, read, flush, sync, seek, close)
+* Tip: callbacks are called synchronously by the goroutine which runs the operation, so they should be fast and must not call the same filesystem instance
+* Tip: OnOpen, OnClose and OnEvict of fspool instances are called after fspool has released its lock, so they may call fspool (e.g. Len or Stats)
+ */
+type Hooks struct {
+	OnOpen  func(fPath string)
+	OnClose func(fPath string, err error)
+	OnEvict func(fPath string, reason string)
+	OnFlush func(fPath string, bytes int, d time.Duration, err error)
+	OnWrite func(fPath string, bytes int, d time.Duration, err error)
+	OnRead  func(fPath string, bytes int, d time.Duration, err error)
+	OnError func(fPath string, op string, err error)
+}
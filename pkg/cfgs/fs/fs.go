@@ -2,34 +2,75 @@ package cfgs
 
 import (
 	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	"github.com/amirvalhalla/fspool/pkg/logger"
+	"os"
 	"time"
 )
 
 var (
 	KB uint64 = 1024
 	MB        = KB * KB
+
+	DefaultFileMode os.FileMode = 0644
 )
 
 /*
 * FSConfiguration is a configuration for each reader or writer instance of you will get from existing fs pool
 * Perm: will define permission of fs
+* fileMode: permission bits of file if it's created by fs (0 means DefaultFileMode)
 * memoryRent: will get specific amount of memory for reading from file or writing into file to speed up the write or read process (unit is byte)
 * flushType: flush type define how to flush into file
 * flushDuration: flushing into disk for each instance by timer
 * flushSize: flushing into disk for each instance by size (unit is byte)
+* readerLimit: limit of readers which each instance can open at the same time (0 means unlimited)
+* flushPolicy: combines flushing by size, time and number of writes, it overrides flushType, flushDuration and flushSize if it's defined
+* flushErrorHandler: will be called when flushing by timer fails, because there isn't any caller to get the error (optional)
+* ioMode: defines how readers and writer access file, PositionalIO (default) uses ReadAt & WriteAt and SeekIO uses Seek followed by Read & Write
+* logger: receives errors which are not returned to any caller (e.g. flushing by time), flushes, syncs and slow operations of fs and its writer (nil means no logging)
+* slowOperationThreshold: operations which take longer than it are reported to logger as slow (0 means disabled)
+* hooks: callbacks of open, close, flush, write, read and error events of fs (optional)
  */
 type FSConfiguration struct {
-	Perm          cfgs.FSPerm
-	MemoryRent    uint64
-	FlushType     cfgs.FlushType
-	FlushDuration time.Duration //depends on FlushType
-	FlushSize     uint64        //depends on FlushType
+	Perm                   cfgs.FSPerm
+	FileMode               os.FileMode
+	MemoryRent             uint64
+	FlushType              cfgs.FlushType
+	FlushDuration          time.Duration //depends on FlushType
+	FlushSize              uint64        //depends on FlushType
+	ReaderLimit            uint32
+	FlushPolicy            cfgs.FlushPolicy
+	FlushErrorHandler      func(fPath string, err error)
+	IOMode                 cfgs.IOMode
+	Logger                 logger.Logger
+	SlowOperationThreshold time.Duration
+	Hooks                  cfgs.Hooks
 }
 
 // New sets default config for FSConfiguration
 func (c *FSConfiguration) New() {
 	c.Perm = cfgs.RW
+	c.FileMode = DefaultFileMode
 	c.MemoryRent = 50 * MB
 	c.FlushType = cfgs.FlushBySize
 	c.FlushSize = 25 * MB
+	c.ReaderLimit = 1
+	c.IOMode = cfgs.PositionalIO
+}
+
+// GetFlushPolicy returns FlushPolicy of configuration, it's made by FlushType, FlushDuration and FlushSize if FlushPolicy is not defined
+func (c FSConfiguration) GetFlushPolicy() cfgs.FlushPolicy {
+	if !c.FlushPolicy.IsZero() {
+		return c.FlushPolicy
+	}
+
+	switch c.FlushType {
+	case cfgs.FlushByTime:
+		return cfgs.FlushPolicy{Age: c.FlushDuration}
+	default:
+		// flush size 0 means flushing on every write
+		if c.FlushSize == 0 {
+			return cfgs.FlushPolicy{Writes: 1}
+		}
+		return cfgs.FlushPolicy{Size: c.FlushSize}
+	}
 }
@@ -3,38 +3,92 @@ package cfgs
 import (
 	"github.com/amirvalhalla/fspool/pkg/cfgs"
 	fsConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
+	"github.com/amirvalhalla/fspool/pkg/logger"
+	"os"
 	"time"
 )
 
+var (
+	DefaultFDHeadroom uint32 = 64
+)
+
 /*
 * FSPoolConfiguration is a configuration for fs pool
 * Perm: will define permission of fspool
 * Tip: if you define ROnly all instances which it will generate is ROnly but you can ovveride configuration of filesystem by fs configuration
 * Tip: if you define WOnly all instances which it will generate is WOnly but you can ovveride configuration of filesystem by fs configuration
 * Tip: if you define RW all instances which it will generate  just have 1 writer and unlimited readers that you can define readerLimit to restrict it
+* fileMode: permission bits of files which are created by fspool (0 means DefaultFileMode of fs configuration)
 * memoryRent: will get specific amount of memory for reading from file or writing into file to speed up the write or read process (unit is byte)
-* limit: limit of getting new filesystem instances
-* readerLimit: limit of getting reader instances from each filesystem instance (not fspool)
+* limit: limit of getting new filesystem instances, 0 means auto which derives it from RLIMIT_NOFILE of process (unix only, readerLimit should be defined for readable instances)
+* fdHeadroom: file descriptors which auto limit leaves for sockets and other files of process (0 means DefaultFDHeadroom)
+* eviction: EvictNone closes instances once they are released, EvictLRU & EvictLFU keep them open and evict an idle one (flush, sync & close) when limit is reached
+* idleTimeout: idle instances which have not been acquired for this duration are flushed, synced & closed by a janitor (0 means never, needs eviction)
+* maxLifetime: instances older than this duration are flushed, synced & closed once they are idle, acquired instances are not handed out anymore and they are closed once released (0 means never)
+* readerLimit: limit of getting reader instances from each filesystem instance (not fspool), 0 means unlimited
 * flushType: flush type define how to flush into file
 * flushDuration: flushing into disk for each instance by timer
 * flushSize: flushing into disk for each instance by size (unit is byte)
+* flushPolicy: combines flushing by size, time and number of writes, it overrides flushType, flushDuration and flushSize if it's defined
+* flushErrorHandler: will be called when flushing of an instance by timer fails (optional)
+* ioMode: defines how instances access files, PositionalIO (default) uses ReadAt & WriteAt and SeekIO uses Seek followed by Read & Write
+* logger: receives errors which are not returned to any caller (e.g. closing evicted instances or flushing by time), flushes, evictions and slow operations of fspool and its instances (nil means no logging)
+* slowOperationThreshold: operations of instances which take longer than it are reported to logger as slow (0 means disabled)
+* hooks: callbacks of lifecycle and I/O events of fspool and its instances, like opening, closing, evicting, flushing, writing, reading and errors
  */
 type FSPoolConfiguration struct {
-	Perm          cfgs.FSPerm    //required
-	MemoryRent    uint64         //required
-	Limit         uint32         //required
-	ReaderLimit   uint32         //required
-	FlushType     cfgs.FlushType //required
-	FlushDuration time.Duration  //required (depends on FlushType)
-	FlushSize     uint64         //required  (depends on FlushType)
+	Perm                   cfgs.FSPerm                   //required
+	FileMode               os.FileMode                   //optional
+	MemoryRent             uint64                        //required
+	Limit                  uint32                        //optional
+	FDHeadroom             uint32                        //optional
+	Eviction               cfgs.EvictionPolicy           //optional
+	IdleTimeout            time.Duration                 //optional
+	MaxLifetime            time.Duration                 //optional
+	ReaderLimit            uint32                        //required
+	FlushType              cfgs.FlushType                //required
+	FlushDuration          time.Duration                 //required (depends on FlushType)
+	FlushSize              uint64                        //required  (depends on FlushType)
+	FlushPolicy            cfgs.FlushPolicy              //optional
+	FlushErrorHandler      func(fPath string, err error) //optional
+	IOMode                 cfgs.IOMode                   //optional
+	Logger                 logger.Logger                 //optional
+	SlowOperationThreshold time.Duration                 //optional
+	Hooks                  cfgs.Hooks                    //optional
 }
 
 func (c FSPoolConfiguration) MapToFsConfiguration() fsConfig.FSConfiguration {
 	return fsConfig.FSConfiguration{
-		Perm:          c.Perm,
-		MemoryRent:    c.MemoryRent,
-		FlushType:     c.FlushType,
-		FlushDuration: c.FlushDuration,
-		FlushSize:     c.FlushSize,
+		Perm:                   c.Perm,
+		FileMode:               c.FileMode,
+		MemoryRent:             c.MemoryRent,
+		FlushType:              c.FlushType,
+		FlushDuration:          c.FlushDuration,
+		FlushSize:              c.FlushSize,
+		ReaderLimit:            c.ReaderLimit,
+		FlushPolicy:            c.FlushPolicy,
+		FlushErrorHandler:      c.FlushErrorHandler,
+		IOMode:                 c.IOMode,
+		Logger:                 c.Logger,
+		SlowOperationThreshold: c.SlowOperationThreshold,
+		Hooks:                  c.Hooks,
+	}
+}
+
+// DescriptorsPerInstance returns number of file descriptors which each filesystem instance may open at once, unlimited readers are counted as one
+// writer needs one more descriptor while it replaces file, because temporary file, directory and the new file are opened before the old one is closed
+func (c FSPoolConfiguration) DescriptorsPerInstance() uint64 {
+	readers := uint64(c.ReaderLimit)
+	if readers == 0 {
+		readers = 1
+	}
+
+	switch c.Perm {
+	case cfgs.ROnly:
+		return readers
+	case cfgs.WOnly:
+		return 2
+	default:
+		return 2 + readers
 	}
 }
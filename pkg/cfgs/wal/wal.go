@@ -0,0 +1,39 @@
+package cfgs
+
+import (
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fsConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
+	"github.com/amirvalhalla/fspool/pkg/logger"
+	"time"
+)
+
+/*
+* WALConfiguration is a configuration for write-ahead log
+* dir: directory which segment files of wal are kept in, it's created if it doesn't exist
+* segmentSize: a new segment file is started when appending a record would make current segment larger than it (unit is byte)
+* syncType: defines how appended records are synced into disk, it reuses flush types of fs
+* Tip: FlushBySize syncs once syncSize bytes have been appended since the last sync, syncSize 0 means each Append is synced before it returns
+* Tip: FlushByTime syncs appended records by timer every syncDuration, so Append doesn't wait for disk
+* syncDuration: syncing into disk by timer (depends on SyncType)
+* syncSize: syncing into disk by size of appended records (unit is byte, depends on SyncType)
+* syncErrorHandler: will be called when syncing by timer fails, because there isn't any caller to get the error (optional)
+* rolloverTimeout: how long rollover waits for a place of the next segment when fspool has reached its limit, 0 means it waits until wal is closed (optional)
+* logger: receives errors, segment rollovers and truncations of wal (nil means no logging)
+ */
+type WALConfiguration struct {
+	Dir              string          //required
+	SegmentSize      uint64          //required
+	SyncType         cfgs.FlushType  //required
+	SyncDuration     time.Duration   //required (depends on SyncType)
+	SyncSize         uint64          //required (depends on SyncType)
+	SyncErrorHandler func(err error) //optional
+	RolloverTimeout  time.Duration   //optional
+	Logger           logger.Logger   //optional
+}
+
+// New sets default config for WALConfiguration, each Append is synced and segments are rolled over every 64 MB
+func (c *WALConfiguration) New() {
+	c.SegmentSize = 64 * fsConfig.MB
+	c.SyncType = cfgs.FlushBySize
+	c.SyncSize = 0
+}
@@ -0,0 +1,69 @@
+// Package errs contains structured error of fspool which keeps underlying cause of failures
+package errs
+
+import (
+	"strconv"
+)
+
+// NoOffset is offset of Error whose operation is not related to any offset of file
+const NoOffset int64 = -1
+
+/*
+* Error describes a failed operation on a file
+* Op: operation which has been failed, like open, read, write, sync, close
+* Path: path of file (empty if layer which has made the error doesn't know it)
+* Offset: offset of file which operation has been run at (NoOffset if it's not related)
+* Err: sentinel error of package which has made the error, errors.Is matches it
+* Cause: underlying error like *fs.PathError or Error of a lower layer, errors.Is & errors.As reach it by unwrapping
+ */
+type Error struct {
+	Op     string
+	Path   string
+	Offset int64
+	Err    error
+	Cause  error
+}
+
+// New provides new Error which matches err and wraps cause
+func New(op string, path string, offset int64, err error, cause error) error {
+	return &Error{
+		Op:     op,
+		Path:   path,
+		Offset: offset,
+		Err:    err,
+		Cause:  cause,
+	}
+}
+
+// Error returns message of sentinel error followed by details of operation and its cause
+func (e *Error) Error() string {
+	msg := e.Err.Error()
+
+	if e.Op != "" {
+		msg += " - " + e.Op
+	}
+
+	if e.Path != "" {
+		msg += " " + e.Path
+	}
+
+	if e.Offset != NoOffset {
+		msg += " at offset " + strconv.FormatInt(e.Offset, 10)
+	}
+
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns underlying cause of Error
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is sentinel error of Error
+func (e *Error) Is(target error) bool {
+	return target == e.Err
+}
@@ -0,0 +1,52 @@
+package errs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+var (
+	errSomeSentinel  = errors.New("package some - could not write")
+	errOtherSentinel = errors.New("package other - could not flush")
+)
+
+func TestError_Is(t *testing.T) {
+	cause := &fs.PathError{Op: "write", Path: "/test/test.txt", Err: syscall.ENOSPC}
+	err := New("write", "", 10, errSomeSentinel, cause)
+
+	assert.ErrorIs(t, err, errSomeSentinel)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.NotErrorIs(t, err, errOtherSentinel)
+}
+
+func TestError_Is_Nested(t *testing.T) {
+	cause := &fs.PathError{Op: "write", Path: "/test/test.txt", Err: syscall.EIO}
+	err := New("flush", "/test/test.txt", NoOffset, errOtherSentinel, New("write", "", 10, errSomeSentinel, cause))
+
+	assert.ErrorIs(t, err, errOtherSentinel)
+	assert.ErrorIs(t, err, errSomeSentinel)
+	assert.ErrorIs(t, err, syscall.EIO)
+
+	var pathErr *fs.PathError
+	assert.True(t, errors.As(err, &pathErr))
+
+	var fsErr *Error
+	assert.True(t, errors.As(err, &fsErr))
+	assert.Equal(t, "flush", fsErr.Op)
+	assert.Equal(t, "/test/test.txt", fsErr.Path)
+}
+
+func TestError_Error(t *testing.T) {
+	err := New("write", "/test/test.txt", 10, errSomeSentinel, syscall.ENOSPC)
+
+	assert.EqualError(t, err, "package some - could not write - write /test/test.txt at offset 10: no space left on device")
+}
+
+func TestError_Error_Without_Details(t *testing.T) {
+	err := New("", "", NoOffset, errSomeSentinel, nil)
+
+	assert.EqualError(t, err, errSomeSentinel.Error())
+}
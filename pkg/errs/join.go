@@ -0,0 +1,58 @@
+package errs
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors is a list of errors which have been made by one operation on several files, errors.Is & errors.As match each of them
+type Errors []error
+
+// Join returns Errors of non-nil errs, nil means there isn't any error
+func Join(errs ...error) error {
+	var joined Errors
+
+	for _, err := range errs {
+		if err != nil {
+			joined = append(joined, err)
+		}
+	}
+
+	if len(joined) == 0 {
+		return nil
+	}
+
+	return joined
+}
+
+// Error returns messages of errors, one per line
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of errors matches target
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error which matches target
+func (e Errors) As(target any) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
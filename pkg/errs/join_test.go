@@ -0,0 +1,34 @@
+package errs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	cause := &fs.PathError{Op: "sync", Path: "/test/test2.txt", Err: syscall.EIO}
+	err := Join(
+		New("close", "/test/test1.txt", NoOffset, errSomeSentinel, nil),
+		nil,
+		New("close", "/test/test2.txt", NoOffset, errOtherSentinel, cause),
+	)
+
+	assert.EqualError(t, err, "package some - could not write - close /test/test1.txt\n"+
+		"package other - could not flush - close /test/test2.txt: sync /test/test2.txt: input/output error")
+	assert.ErrorIs(t, err, errSomeSentinel)
+	assert.ErrorIs(t, err, errOtherSentinel)
+	assert.ErrorIs(t, err, syscall.EIO)
+
+	var pathErr *fs.PathError
+
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/test/test2.txt", pathErr.Path)
+}
+
+func TestJoin_Nil(t *testing.T) {
+	assert.Nil(t, Join())
+	assert.Nil(t, Join(nil, nil))
+}
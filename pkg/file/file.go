@@ -34,4 +34,7 @@ type FileHelper interface {
 	Stat(path string) (os.FileInfo, error)
 	IsNotExist(err error) bool
 	MkdirAll(path string, perm os.FileMode) error
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Rename(oldPath, newPath string) error
+	Remove(path string) error
 }
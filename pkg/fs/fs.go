@@ -2,21 +2,29 @@
 package fs
 
 import (
+	"context"
 	"errors"
 	"github.com/amirvalhalla/fspool/pkg/cfgs"
 	fsConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
-	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/logger"
 	"github.com/amirvalhalla/fspool/pkg/reader"
 	"github.com/amirvalhalla/fspool/pkg/writer"
 	"github.com/google/uuid"
-	"log"
+	"io"
+	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 var (
 	ErrFilesystemFilepathIsEmpty                 = errors.New("package fs - could not create new file system instance - path is empty")
 	ErrFilesystemMemoryRentConflictWithFlushSize = errors.New("package fs - filesystem memory rent size always should be greater than flush size")
+	ErrFilesystemFlushDurationIsZero             = errors.New("package fs - filesystem flush duration should be greater than zero")
 	ErrFilesystemCouldNotWrite                   = errors.New("package fs - filesystem could not write")
+	ErrFilesystemCouldNotFlush                   = errors.New("package fs - filesystem could not flush buffered data into file")
+	ErrFilesystemInvalidSeek                     = errors.New("package fs - filesystem could not resolve offset of seek option")
 	ErrFilesystemWriterNil                       = errors.New("package fs - writer instance of filesystem has been closed or doesn't initialized")
 	ErrFilesystemWriterCouldNotSync              = errors.New("package fs - writer instance of filesystem could not sync")
 	ErrFilesystemCouldNotCloseWriter             = errors.New("package fs - filesystem could not close writer instance")
@@ -25,53 +33,119 @@ var (
 	ErrFilesystemCouldNotReadAllData             = errors.New("package fs - filesystem could not read all data")
 	ErrFilesystemReaderNil                       = errors.New("package fs - reader instance of filesystem has been closed or doesn't initialized")
 	ErrFilesystemCouldNotCloseReader             = errors.New("package fs - filesystem could not close reader")
+	ErrFilesystemCouldNotClose                   = errors.New("package fs - filesystem could not close")
+	ErrFilesystemCouldNotOpenFile                = errors.New("package fs - filesystem could not open file")
+	ErrFilesystemCouldNotOpenReader              = errors.New("package fs - filesystem could not open new reader")
+	ErrFilesystemReaderIsNotAcquired             = errors.New("package fs - reader instance has not been acquired from filesystem")
+	ErrFilesystemContextDone                     = errors.New("package fs - filesystem has given up waiting because context is done")
+	ErrFilesystemCouldNotReplace                 = errors.New("package fs - filesystem could not replace file")
+	ErrFilesystemWriterBroken                    = errors.New("package fs - writer instance of filesystem is broken because it could not be reopened on the replaced file")
+	ErrFilesystemReplacedButNotReopened          = errors.New("package fs - file has been replaced but filesystem could not sync its directory or reopen writer or readers on the new file")
 )
 
 type Filesystem interface {
-	// Write will write or update raw data into file
+	// Write will write or update raw data into file, data will be kept in memory rent until it's flushed
 	Write(rawData []byte, offset int64, seek int) error
-	// Sync will sync data from in-memory to disk
+	// WriteContext is Write which gives up waiting for writer when ctx is done
+	WriteContext(ctx context.Context, rawData []byte, offset int64, seek int) error
+	// Append will write raw data at the end of file and returns offset which data has been written at, so callers don't race on offsets
+	Append(rawData []byte) (int64, error)
+	// AppendContext is Append which gives up waiting for writer when ctx is done
+	AppendContext(ctx context.Context, rawData []byte) (int64, error)
+	// ReplaceAll will atomically replace whole content of file with raw data, so readers see either the previous content or raw data
+	// raw data is written into a temporary file which is renamed over file, buffered data of previous content is dropped
+	// writer methods fail by ErrFilesystemWriterBroken if writer could not be reopened on the new file
+	// ErrFilesystemReplacedButNotReopened means file has been replaced though, while ErrFilesystemCouldNotReplace means it has not
+	ReplaceAll(rawData []byte) error
+	// ReplaceAllContext is ReplaceAll which gives up waiting for writer when ctx is done
+	ReplaceAllContext(ctx context.Context, rawData []byte) error
+	// Sync will flush buffered data into file and sync data from in-memory to disk, concurrent callers share fsyncs
 	Sync() error
+	// SyncContext is Sync which gives up waiting for writer or fsync when ctx is done
+	SyncContext(ctx context.Context) error
+	// Flush will write buffered data into file without syncing it to disk, it's a barrier for flush policy
+	Flush() error
+	// FlushContext is Flush which gives up waiting for writer when ctx is done
+	FlushContext(ctx context.Context) error
 	// GetWriterId return id of writer instance
 	GetWriterId() (uuid.UUID, error)
-	// CloseWriter will close writer of filesystem instance
+	// CloseWriter will close writer of filesystem instance, writer methods fail by ErrFilesystemWriterNil after it
 	CloseWriter() error
 	// ReadData func provides reading data from file by defining custom pos & seek option
+	// it returns io.EOF if nothing could be read and io.ErrUnexpectedEOF with read bytes if file ends before length
 	ReadData(offset int64, length int, seek int) ([]byte, error)
-	// ReadAllData func provides reading all data from file
+	// ReadDataContext is ReadData which waits for a free reader and gives up waiting for flushing or a reader when ctx is done
+	ReadDataContext(ctx context.Context, offset int64, length int, seek int) ([]byte, error)
+	// ReadAllData func provides reading all data from file from its beginning
 	ReadAllData() ([]byte, error)
+	// ReadAllDataContext is ReadAllData which waits for a free reader and gives up waiting for flushing or a reader when ctx is done
+	ReadAllDataContext(ctx context.Context) ([]byte, error)
 	// GetReaderId return id of reader instance
 	GetReaderId() (uuid.UUID, error)
 	// CloseReader func provides close reader of filesystem instance
 	CloseReader() error
 	// GetReaderState return state of reader instance
 	GetReaderState() (bool, error)
+	// AcquireReader hands out a free reader of filesystem instance, it should be given back by ReleaseReader
+	AcquireReader() (reader.FileReader, error)
+	// AcquireReaderContext is AcquireReader which waits for a free reader until ctx is done instead of returning ErrFilesystemReaderOccupying
+	AcquireReaderContext(ctx context.Context) (reader.FileReader, error)
+	// ReleaseReader gives back reader which has been acquired by AcquireReader
+	ReleaseReader(r reader.FileReader) error
+	// Close will flush buffered data and close writer and readers of filesystem instance, they're closed even if flushing fails
+	Close() error
+	// Stats returns a snapshot of counters of filesystem instance
+	Stats() Stats
 }
 
 type filesystem struct {
-	buff        []byte
-	filePath    string
-	dirPath     string
-	config      fsConfig.FSConfiguration
-	readerState bool // false means free and true means occupying
-	reader      reader.FileReader
-	writer      writer.FileWriter
+	writerMu     mutex
+	buff         []byte // buffered data which has not been flushed into file yet, its capacity is config.MemoryRent
+	buffOffset   int64  // offset of file which first byte of buff belongs to
+	buffWrites   uint64 // number of writes which have been buffered since the last flush
+	writerPos    int64  // offset of file which next write continues from (used by io.SeekCurrent)
+	tail         int64  // end of file including buffered data which Append writes at
+	tailKnown    bool   // false means tail should be recovered from size of file
+	filePath     string
+	dirPath      string
+	config       fsConfig.FSConfiguration
+	flushPolicy  cfgs.FlushPolicy
+	log          logger.Logger
+	readersMu    sync.Mutex
+	readers      []reader.FileReader // all opened readers, each of them has its own file
+	freeReaders  []reader.FileReader
+	readerFreed  chan struct{}       // closed and replaced when a reader is released or readers are closed
+	staleReaders []reader.FileReader // acquired readers of replaced file, they're reopened once they're released
+	readerClosed bool                // true once readers have been closed or filesystem has been opened without them, no reader is handed out then
+	writer       writer.FileWriter
+	writerErr    error // non-nil once writer has been closed or broken, writer methods fail by it (guarded by writerMu)
+	openFileFunc OpenFile
+	renameFunc   Rename
+	removeFunc   Remove
+	flusherStop  chan struct{}
+	flusherOnce  sync.Once
+	flusherWg    sync.WaitGroup
+	stats        stats
 }
 
 // NewFilesystem provide new instance of filesystem with readers and writer based on your configuration
-func NewFilesystem(fPath string, config fsConfig.FSConfiguration, file file.File, statFunc Stat, isNotExistFunc IsNotExist, mkdirAllFunc MkdirAll) (Filesystem, error) {
+// writer and each reader have their own file of fPath which is opened (or created by writer) by openFileFunc, readers will be opened up to config.ReaderLimit
+func NewFilesystem(fPath string, config fsConfig.FSConfiguration, statFunc Stat, isNotExistFunc IsNotExist, mkdirAllFunc MkdirAll, openFileFunc OpenFile, renameFunc Rename, removeFunc Remove) (Filesystem, error) {
 	var dirPath string
 	var fWriter writer.FileWriter
-	var fReader reader.FileReader
 
 	if fPath == "" || len(fPath) <= 0 {
 		return nil, ErrFilesystemFilepathIsEmpty
 	}
 
-	if config.FlushType == cfgs.FlushBySize {
-		if config.MemoryRent < config.FlushSize {
-			return nil, ErrFilesystemMemoryRentConflictWithFlushSize
-		}
+	flushPolicy := config.GetFlushPolicy()
+
+	if config.MemoryRent < flushPolicy.Size {
+		return nil, ErrFilesystemMemoryRentConflictWithFlushSize
+	}
+
+	if config.FlushPolicy.IsZero() && config.FlushType == cfgs.FlushByTime && config.FlushDuration <= 0 {
+		return nil, ErrFilesystemFlushDurationIsZero
 	}
 
 	if config.Perm == cfgs.ROnly {
@@ -87,49 +161,380 @@ func NewFilesystem(fPath string, config fsConfig.FSConfiguration, file file.File
 		}
 	}
 
-	switch config.Perm {
-	case cfgs.ROnly:
-		fReader, _ = reader.NewFileReader(file)
-	case cfgs.WOnly:
-		fWriter, _ = writer.NewFileWriter(file)
-	case cfgs.RW:
-		fReader, _ = reader.NewFileReader(file)
-		fWriter, _ = writer.NewFileWriter(file)
+	if config.Perm != cfgs.ROnly {
+		wFile, err := openFileFunc(fPath, writerFlag(config.Perm), fileMode(config))
+		if err != nil {
+			return nil, errs.New("open", fPath, errs.NoOffset, ErrFilesystemCouldNotOpenFile, err)
+		}
+
+		fWriter, _ = writer.NewFileWriter(wFile, config.IOMode, config.Logger)
+	}
+
+	f := &filesystem{
+		writerMu:     newMutex(),
+		readerFreed:  make(chan struct{}),
+		buff:         make([]byte, 0, config.MemoryRent),
+		filePath:     fPath,
+		dirPath:      dirPath,
+		config:       config,
+		flushPolicy:  flushPolicy,
+		log:          logger.OrNop(config.Logger),
+		writer:       fWriter,
+		openFileFunc: openFileFunc,
+		renameFunc:   renameFunc,
+		removeFunc:   removeFunc,
 	}
 
-	return &filesystem{
-		buff:     make([]byte, config.MemoryRent),
-		filePath: fPath,
-		dirPath:  dirPath,
-		config:   config,
-		reader:   fReader,
-		writer:   fWriter,
-	}, nil
+	// reader has its own file, so seeking or closing it doesn't affect writer
+	f.readerClosed = config.Perm == cfgs.WOnly
+	if config.Perm != cfgs.WOnly {
+		fReader, err := f.openReader()
+		if err != nil {
+			if fWriter != nil {
+				_ = fWriter.Close()
+			}
+			return nil, f.newError("open", errs.NoOffset, ErrFilesystemCouldNotOpenFile, err)
+		}
+
+		f.readers = []reader.FileReader{fReader}
+		f.freeReaders = []reader.FileReader{fReader}
+	}
+
+	if fWriter != nil && flushPolicy.Age > 0 {
+		f.startFlusher()
+	}
+
+	if config.Hooks.OnOpen != nil {
+		config.Hooks.OnOpen(fPath)
+	}
+
+	return f, nil
 }
 
-// Write will write or update raw data into file
+// Write will write or update raw data into file, data will be kept in memory rent until it's flushed
 func (f *filesystem) Write(rawData []byte, offset int64, seek int) error {
+	return f.WriteContext(context.Background(), rawData, offset, seek)
+}
+
+// WriteContext is Write which gives up waiting for writer when ctx is done, writing into file can't be canceled after it's started
+func (f *filesystem) WriteContext(ctx context.Context, rawData []byte, offset int64, seek int) (err error) {
+	defer f.observe("write", time.Now())
+
+	start := time.Now()
+	defer func() { f.hookIO(f.config.Hooks.OnWrite, len(rawData), start, err) }()
+
+	if err := f.validateWriter(); err != nil {
+		return err
+	}
+
+	if err := f.lockOpenWriter(ctx, "write"); err != nil {
+		return err
+	}
+	defer f.writerMu.Unlock()
+
+	pos, err := f.resolveOffset(offset, seek)
+	if err != nil {
+		return err
+	}
+
+	return f.write(rawData, pos)
+}
+
+// Append will write raw data at the end of file and returns offset which data has been written at
+func (f *filesystem) Append(rawData []byte) (int64, error) {
+	return f.AppendContext(context.Background(), rawData)
+}
+
+// AppendContext is Append which gives up waiting for writer when ctx is done, writing into file can't be canceled after it's started
+func (f *filesystem) AppendContext(ctx context.Context, rawData []byte) (offset int64, err error) {
+	defer f.observe("append", time.Now())
+
+	start := time.Now()
+	defer func() { f.hookIO(f.config.Hooks.OnWrite, len(rawData), start, err) }()
+
+	if err := f.validateWriter(); err != nil {
+		return 0, err
+	}
+
+	if err := f.lockOpenWriter(ctx, "append"); err != nil {
+		return 0, err
+	}
+	defer f.writerMu.Unlock()
+
+	// end of file is recovered from its size once, then it's kept by writes of filesystem including buffered ones
+	if !f.tailKnown {
+		size, err := f.writer.Size()
+		if err != nil {
+			return 0, f.newError("append", errs.NoOffset, ErrFilesystemCouldNotWrite, err)
+		}
+
+		if size > f.tail {
+			f.tail = size
+		}
+		f.tailKnown = true
+	}
+
+	pos := f.tail
+
+	if err := f.write(rawData, pos); err != nil {
+		return 0, err
+	}
+
+	return pos, nil
+}
+
+// write writes raw data at pos of file through memory rent (caller must hold f.writerMu)
+func (f *filesystem) write(rawData []byte, pos int64) error {
+	// buff only holds contiguous data, so it should be flushed before writing somewhere else or overflowing
+	if len(f.buff) > 0 && (pos != f.buffOffset+int64(len(f.buff)) || len(f.buff)+len(rawData) > cap(f.buff)) {
+		if err := f.flush(); err != nil {
+			return f.newError("write", pos, ErrFilesystemCouldNotWrite, err)
+		}
+	}
+
+	if len(rawData) > cap(f.buff) {
+		if err := f.writer.Write(rawData, pos, io.SeekStart); err != nil {
+			return f.newError("write", pos, ErrFilesystemCouldNotWrite, err)
+		}
+		f.writerPos = pos + int64(len(rawData))
+		f.moveTail(rawData, pos)
+		f.stats.write(len(rawData))
+		return nil
+	}
+
+	buffLen, writerPos, tail := len(f.buff), f.writerPos, f.tail
+
+	if len(f.buff) == 0 {
+		f.buffOffset = pos
+	}
+	f.buff = append(f.buff, rawData...)
+	f.buffWrites++
+	f.stats.buffered.Store(uint64(len(f.buff)))
+	f.writerPos = pos + int64(len(rawData))
+	f.moveTail(rawData, pos)
+
+	if f.isFlushRequired() {
+		if err := f.flush(); err != nil {
+			// raw data is taken back from memory rent, so a failed write doesn't reach file by a later flush
+			f.buff = f.buff[:buffLen]
+			f.buffWrites--
+			f.stats.buffered.Store(uint64(buffLen))
+			f.writerPos, f.tail = writerPos, tail
+			return f.newError("write", pos, ErrFilesystemCouldNotWrite, err)
+		}
+	}
+
+	f.stats.write(len(rawData))
+
+	return nil
+}
+
+// moveTail moves end of file after raw data which has been written at pos (caller must hold f.writerMu)
+func (f *filesystem) moveTail(rawData []byte, pos int64) {
+	if end := pos + int64(len(rawData)); end > f.tail {
+		f.tail = end
+	}
+}
+
+// ReplaceAll will atomically replace whole content of file with raw data, so readers see either the previous content or raw data
+func (f *filesystem) ReplaceAll(rawData []byte) error {
+	return f.ReplaceAllContext(context.Background(), rawData)
+}
+
+// ReplaceAllContext is ReplaceAll which gives up waiting for writer when ctx is done, replacing can't be canceled after it's started
+// raw data is written & synced into a temporary file of the same directory which is renamed over file, then directory is synced
+// writer and free readers are reopened on the new file, acquired readers keep reading the previous content until they're released
+func (f *filesystem) ReplaceAllContext(ctx context.Context, rawData []byte) (err error) {
+	defer f.observe("replace", time.Now())
+
+	start := time.Now()
+	defer func() { f.hookIO(f.config.Hooks.OnWrite, len(rawData), start, err) }()
 
 	if err := f.validateWriter(); err != nil {
 		return err
 	}
 
-	if err := f.writer.Write(rawData, offset, seek); err != nil {
-		return ErrFilesystemCouldNotWrite
+	if err := f.lockOpenWriter(ctx, "replace"); err != nil {
+		return err
+	}
+	defer f.writerMu.Unlock()
+
+	tmpPath, err := f.createReplacement(rawData)
+	if err != nil {
+		return f.newError("replace", errs.NoOffset, ErrFilesystemCouldNotReplace, err)
+	}
+
+	if err := f.renameFunc(tmpPath, f.filePath); err != nil {
+		_ = f.removeFunc(tmpPath)
+		return f.newError("replace", errs.NoOffset, ErrFilesystemCouldNotReplace, err)
+	}
+
+	// buffered data belongs to the previous content which doesn't exist anymore
+	f.buff = f.buff[:0]
+	f.buffOffset = 0
+	f.buffWrites = 0
+	f.stats.buffered.Store(0)
+	f.writerPos = int64(len(rawData))
+	f.tail = int64(len(rawData))
+	f.tailKnown = true
+	f.stats.write(len(rawData))
+
+	// file has been replaced, so writer and readers are reopened even if syncing directory fails
+	dirErr := SyncDirectory(filepath.Dir(f.filePath), f.openFileFunc)
+
+	// writer which still points to the replaced file would write into an unlinked file, so it's closed and writer methods fail after it
+	wErr := f.reopenWriter()
+	if wErr != nil {
+		_ = f.writer.Close()
+		f.writerErr = ErrFilesystemWriterBroken
+	}
+
+	err = errs.Join(dirErr, wErr, f.reopenReaders())
+	if err != nil {
+		return f.newError("replace", errs.NoOffset, ErrFilesystemReplacedButNotReopened, err)
 	}
 
 	return nil
 }
 
-// Sync will sync data from in-memory to disk
+// createReplacement writes & syncs raw data into a new temporary file next to file and returns its path, it's removed if anything fails
+func (f *filesystem) createReplacement(rawData []byte) (string, error) {
+	tmpPath := filepath.Join(filepath.Dir(f.filePath), "."+filepath.Base(f.filePath)+".replace-"+uuid.NewString())
+
+	tmp, err := f.openFileFunc(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fileMode(f.config))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(rawData)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+
+	if err != nil {
+		_ = f.removeFunc(tmpPath)
+		return "", err
+	}
+
+	return tmpPath, nil
+}
+
+// reopenWriter points writer to the new file of filesystem and closes the replaced one (caller must hold f.writerMu)
+func (f *filesystem) reopenWriter() error {
+	wFile, err := f.openFileFunc(f.filePath, writerFlag(f.config.Perm), fileMode(f.config))
+	if err != nil {
+		return err
+	}
+
+	return f.writer.Replace(wFile)
+}
+
+// reopenReaders replaces free readers with readers of the new file of filesystem, acquired ones are reopened by ReleaseReader
+func (f *filesystem) reopenReaders() error {
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
+
+	var err error
+
+	for _, r := range f.readers {
+		if !containsReader(f.freeReaders, r) && !containsReader(f.staleReaders, r) {
+			f.staleReaders = append(f.staleReaders, r)
+		}
+	}
+
+	free := f.freeReaders
+	f.freeReaders = nil
+
+	for _, r := range free {
+		if rErr := f.reopenReader(r); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+
+	return err
+}
+
+// reopenReader closes reader of replaced file and puts a free reader of the new file in its place (caller must hold f.readersMu)
+// it's forgotten if opening fails, so its place is taken by a reader which is opened on demand by acquireReader
+func (f *filesystem) reopenReader(r reader.FileReader) error {
+	_ = r.Close()
+
+	fReader, err := f.openReader()
+	if err != nil {
+		f.readers = removeReader(f.readers, r)
+		f.notifyReaderFreed()
+		return err
+	}
+
+	for i := range f.readers {
+		if f.readers[i] == r {
+			f.readers[i] = fReader
+		}
+	}
+	f.freeReaders = append(f.freeReaders, fReader)
+	f.notifyReaderFreed()
+
+	return nil
+}
+
+// Sync will flush buffered data into file and sync data from in-memory to disk
 func (f *filesystem) Sync() error {
+	return f.SyncContext(context.Background())
+}
+
+// SyncContext is Sync which gives up waiting for writer or fsync when ctx is done, flushing and syncing can't be canceled after they're started
+func (f *filesystem) SyncContext(ctx context.Context) error {
+	defer f.observe("sync", time.Now())
 
 	if err := f.validateWriter(); err != nil {
 		return err
 	}
 
-	if err := f.writer.Sync(); err != nil {
-		return ErrFilesystemWriterCouldNotSync
+	if err := f.lockOpenWriter(ctx, "sync"); err != nil {
+		return err
+	}
+
+	err := f.flush()
+	f.writerMu.Unlock()
+
+	if err != nil {
+		return f.newError("flush", errs.NoOffset, ErrFilesystemCouldNotFlush, err)
+	}
+
+	// writer is synced without holding f.writerMu, so concurrent callers share fsyncs by group commit of writer and writes are not blocked meanwhile
+	if err := f.writer.SyncContext(ctx); err != nil {
+		if errors.Is(err, writer.ErrFileWriterContextDone) {
+			return f.newError("sync", errs.NoOffset, ErrFilesystemContextDone, err)
+		}
+		return f.newError("sync", errs.NoOffset, ErrFilesystemWriterCouldNotSync, err)
+	}
+
+	return nil
+}
+
+// Flush will write buffered data into file without syncing it to disk, it's a barrier for flush policy
+func (f *filesystem) Flush() error {
+	return f.FlushContext(context.Background())
+}
+
+// FlushContext is Flush which gives up waiting for writer when ctx is done, flushing can't be canceled after it's started
+func (f *filesystem) FlushContext(ctx context.Context) error {
+	defer f.observe("flush", time.Now())
+
+	if err := f.validateWriter(); err != nil {
+		return err
+	}
+
+	if err := f.lockOpenWriter(ctx, "flush"); err != nil {
+		return err
+	}
+	defer f.writerMu.Unlock()
+
+	if err := f.flush(); err != nil {
+		return f.newError("flush", errs.NoOffset, ErrFilesystemCouldNotFlush, err)
 	}
 
 	return nil
@@ -152,82 +557,517 @@ func (f *filesystem) CloseWriter() error {
 		return err
 	}
 
-	if err := f.writer.Close(); err != nil {
-		return ErrFilesystemCouldNotCloseWriter
+	f.stopFlusher()
+
+	f.writerMu.Lock()
+	defer f.writerMu.Unlock()
+
+	if f.writerErr != nil {
+		return f.writerErr
 	}
 
-	return nil
+	return f.closeWriter(ErrFilesystemCouldNotCloseWriter)
 }
 
 // ReadData func provides reading data from file by defining custom pos & seek option
 func (f *filesystem) ReadData(offset int64, length int, seek int) ([]byte, error) {
+	return f.readData(context.Background(), f.AcquireReader, offset, length, seek)
+}
 
-	if err := f.validateReader(); err != nil {
+// ReadDataContext is ReadData which waits for a free reader and gives up waiting for flushing or a reader when ctx is done
+func (f *filesystem) ReadDataContext(ctx context.Context, offset int64, length int, seek int) ([]byte, error) {
+	return f.readData(ctx, func() (reader.FileReader, error) {
+		return f.acquireReaderContext(ctx, "read")
+	}, offset, length, seek)
+}
+
+// readData reads data from file by a reader which is given by acquireReader
+func (f *filesystem) readData(ctx context.Context, acquireReader func() (reader.FileReader, error), offset int64, length int, seek int) (rawData []byte, err error) {
+	defer f.observe("read", time.Now())
+
+	start := time.Now()
+	defer func() { f.hookIO(f.config.Hooks.OnRead, len(rawData), start, err) }()
+
+	if err := f.flushBeforeRead(ctx, "read"); err != nil {
+		return nil, err
+	}
+
+	r, err := acquireReader()
+	if err != nil {
 		return nil, err
 	}
 
-	f.readerState = true
-	rawData, err := f.reader.ReadData(offset, length, seek)
-	f.readerState = false
+	rawData, err = r.ReadData(offset, length, seek)
+	_ = f.ReleaseReader(r)
+
+	// reaching end of file is reported as it is, so caller gets bytes which have been read before it
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		f.stats.read(len(rawData))
+		return rawData, err
+	}
 
 	if err != nil {
-		log.Println(ErrFilesystemCouldNotReadData.Error())
-		return nil, ErrFilesystemCouldNotReadData
+		return nil, f.newError("read", offset, ErrFilesystemCouldNotReadData, err)
 	}
 
+	f.stats.read(len(rawData))
+
 	return rawData, nil
 }
 
 // ReadAllData func provides reading all data from file
 func (f *filesystem) ReadAllData() ([]byte, error) {
+	return f.readAllData(context.Background(), f.AcquireReader)
+}
 
-	if err := f.validateReader(); err != nil {
+// ReadAllDataContext is ReadAllData which waits for a free reader and gives up waiting for flushing or a reader when ctx is done
+func (f *filesystem) ReadAllDataContext(ctx context.Context) ([]byte, error) {
+	return f.readAllData(ctx, func() (reader.FileReader, error) {
+		return f.acquireReaderContext(ctx, "read")
+	})
+}
+
+// readAllData reads all data from file by a reader which is given by acquireReader
+func (f *filesystem) readAllData(ctx context.Context, acquireReader func() (reader.FileReader, error)) (rawData []byte, err error) {
+	defer f.observe("read all", time.Now())
+
+	start := time.Now()
+	defer func() { f.hookIO(f.config.Hooks.OnRead, len(rawData), start, err) }()
+
+	if err := f.flushBeforeRead(ctx, "read"); err != nil {
 		return nil, err
 	}
 
-	f.readerState = true
-	rawData, err := f.reader.ReadAllData()
-	f.readerState = false
+	r, err := acquireReader()
+	if err != nil {
+		return nil, err
+	}
+
+	rawData, err = r.ReadAllData()
+	_ = f.ReleaseReader(r)
 
 	if err != nil {
-		log.Println(ErrFilesystemCouldNotReadAllData.Error())
-		return nil, ErrFilesystemCouldNotReadAllData
+		return nil, f.newError("read", 0, ErrFilesystemCouldNotReadAllData, err)
 	}
 
+	f.stats.read(len(rawData))
+
 	return rawData, nil
 }
 
 // GetReaderId return id of reader instance
 func (f *filesystem) GetReaderId() (uuid.UUID, error) {
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
 
-	if f.reader == nil {
-		return uuid.Nil, ErrFilesystemReaderNil
+	if err := f.validateReader(); err != nil {
+		return uuid.Nil, err
 	}
 
-	return f.reader.GetId(), nil
+	// readers which could not be reopened on the replaced file are opened again on demand
+	if len(f.readers) == 0 {
+		r, err := f.acquireReader()
+		if err != nil {
+			return uuid.Nil, err
+		}
+		f.freeReaders = append(f.freeReaders, r)
+	}
+
+	return f.readers[0].GetId(), nil
 }
 
-// CloseReader func provides close reader of filesystem instance
+// CloseReader func provides close readers of filesystem instance, it fails if any reader is acquired and after closing no reader is handed out anymore
 func (f *filesystem) CloseReader() error {
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
 
 	if err := f.validateReader(); err != nil {
 		return err
 	}
 
-	if err := f.reader.Close(); err != nil {
-		return ErrFilesystemCouldNotCloseReader
+	if len(f.freeReaders) != len(f.readers) {
+		return ErrFilesystemReaderOccupying
 	}
 
-	return nil
+	return f.closeReaders(ErrFilesystemCouldNotCloseReader)
 }
 
-// GetReaderState return state of reader instance
+// GetReaderState return state of reader instance, true means all readers are occupying and filesystem can't open another one
 func (f *filesystem) GetReaderState() (bool, error) {
-	if f.reader == nil {
-		return false, ErrFilesystemReaderNil
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
+
+	if err := f.validateReader(); err != nil {
+		return false, err
+	}
+
+	return len(f.freeReaders) == 0 && f.isReaderLimitReached(), nil
+}
+
+// AcquireReader hands out a free reader of filesystem instance, it should be given back by ReleaseReader
+// a new reader will be opened if there isn't any free reader and config.ReaderLimit has not been reached
+func (f *filesystem) AcquireReader() (reader.FileReader, error) {
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
+
+	return f.acquireReader()
+}
+
+// AcquireReaderContext is AcquireReader which waits for a free reader until ctx is done instead of returning ErrFilesystemReaderOccupying
+func (f *filesystem) AcquireReaderContext(ctx context.Context) (reader.FileReader, error) {
+	return f.acquireReaderContext(ctx, "acquire reader")
+}
+
+// acquireReaderContext waits for a free reader until ctx is done and returns error of ctx wrapped with op
+func (f *filesystem) acquireReaderContext(ctx context.Context, op string) (reader.FileReader, error) {
+	for {
+		f.readersMu.Lock()
+		r, err := f.acquireReader()
+		freed := f.readerFreed
+		f.readersMu.Unlock()
+
+		if !errors.Is(err, ErrFilesystemReaderOccupying) {
+			return r, err
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, f.newError(op, errs.NoOffset, ErrFilesystemContextDone, ctx.Err())
+		}
+	}
+}
+
+// acquireReader hands out a free reader or opens a new one if config.ReaderLimit has not been reached (caller must hold f.readersMu)
+func (f *filesystem) acquireReader() (reader.FileReader, error) {
+	if err := f.validateReader(); err != nil {
+		return nil, err
+	}
+
+	if n := len(f.freeReaders); n > 0 {
+		r := f.freeReaders[n-1]
+		f.freeReaders = f.freeReaders[:n-1]
+		return r, nil
+	}
+
+	if f.isReaderLimitReached() {
+		return nil, ErrFilesystemReaderOccupying
+	}
+
+	r, err := f.openReader()
+	if err != nil {
+		return nil, f.newError("open", errs.NoOffset, ErrFilesystemCouldNotOpenReader, err)
+	}
+
+	f.readers = append(f.readers, r)
+
+	return r, nil
+}
+
+// ReleaseReader gives back reader which has been acquired by AcquireReader
+func (f *filesystem) ReleaseReader(r reader.FileReader) error {
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
+
+	if !containsReader(f.readers, r) || containsReader(f.freeReaders, r) {
+		return ErrFilesystemReaderIsNotAcquired
+	}
+
+	// file has been replaced while reader was acquired
+	if containsReader(f.staleReaders, r) {
+		f.staleReaders = removeReader(f.staleReaders, r)
+		if err := f.reopenReader(r); err != nil {
+			return f.newError("open", errs.NoOffset, ErrFilesystemCouldNotOpenReader, err)
+		}
+		return nil
+	}
+
+	f.freeReaders = append(f.freeReaders, r)
+	f.notifyReaderFreed()
+
+	return nil
+}
+
+// Close will flush buffered data and close writer and readers of filesystem instance
+func (f *filesystem) Close() (err error) {
+	defer func() {
+		if f.config.Hooks.OnClose != nil {
+			f.config.Hooks.OnClose(f.filePath, err)
+		}
+	}()
+
+	var writerErr error
+
+	// writer and readers are always closed, so a failed flush doesn't leak their files
+	if f.writer != nil {
+		f.stopFlusher()
+
+		f.writerMu.Lock()
+		if f.writerErr == nil {
+			writerErr = f.closeWriter(ErrFilesystemCouldNotClose)
+		}
+		f.writerMu.Unlock()
+	}
+
+	f.readersMu.Lock()
+	defer f.readersMu.Unlock()
+
+	return errs.Join(writerErr, f.closeReaders(ErrFilesystemCouldNotClose))
+}
+
+// closeWriter flushes buffered data and closes writer even if flushing fails, buffered data is dropped then (caller must hold f.writerMu)
+func (f *filesystem) closeWriter(closeErr error) error {
+	var flushErr, err error
+
+	if fErr := f.flush(); fErr != nil {
+		flushErr = f.newError("flush", errs.NoOffset, ErrFilesystemCouldNotFlush, fErr)
+		f.buff = f.buff[:0]
+		f.buffWrites = 0
+		f.stats.buffered.Store(0)
+	}
+
+	if cErr := f.writer.Close(); cErr != nil {
+		err = f.newError("close", errs.NoOffset, closeErr, cErr)
+	}
+
+	f.writerErr = ErrFilesystemWriterNil
+
+	return errs.Join(flushErr, err)
+}
+
+// closeReaders closes all readers and forgets them, so closed readers are never handed out again (caller must hold f.readersMu)
+func (f *filesystem) closeReaders(closeErr error) error {
+	var err error
+
+	for _, r := range f.readers {
+		if cErr := r.Close(); cErr != nil && err == nil {
+			err = f.newError("close", errs.NoOffset, closeErr, cErr)
+		}
+	}
+
+	f.readers = nil
+	f.freeReaders = nil
+	f.staleReaders = nil
+	f.readerClosed = true
+	f.notifyReaderFreed()
+
+	return err
+}
+
+// notifyReaderFreed wakes callers of AcquireReaderContext up which are waiting for a free reader (caller must hold f.readersMu)
+func (f *filesystem) notifyReaderFreed() {
+	close(f.readerFreed)
+	f.readerFreed = make(chan struct{})
+}
+
+// flush writes buffered data into file (caller must hold f.writerMu)
+func (f *filesystem) flush() error {
+	if len(f.buff) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+
+	if err := f.writer.Write(f.buff, f.buffOffset, io.SeekStart); err != nil {
+		f.hookIO(f.config.Hooks.OnFlush, len(f.buff), start, err)
+		return err
+	}
+
+	d := time.Since(start)
+	f.stats.flush(d)
+	f.hookIO(f.config.Hooks.OnFlush, len(f.buff), start, nil)
+
+	f.log.Debug("package fs - flushed memory rent into file", "path", f.filePath, "offset", f.buffOffset, "bytes", len(f.buff), "duration", d)
+
+	f.buff = f.buff[:0]
+	f.buffWrites = 0
+	f.stats.buffered.Store(0)
+
+	return nil
+}
+
+// isFlushRequired reports whether buffered data has reached size or number of writes of flush policy (caller must hold f.writerMu)
+func (f *filesystem) isFlushRequired() bool {
+	if f.flushPolicy.Size > 0 && uint64(len(f.buff)) >= f.flushPolicy.Size {
+		return true
+	}
+
+	return f.flushPolicy.Writes > 0 && f.buffWrites >= f.flushPolicy.Writes
+}
+
+// startFlusher runs a goroutine which flushes buffered data into file every Age of flush policy
+func (f *filesystem) startFlusher() {
+	f.flusherStop = make(chan struct{})
+	f.flusherWg.Add(1)
+
+	go func() {
+		defer f.flusherWg.Done()
+
+		ticker := time.NewTicker(f.flushPolicy.Age)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-f.flusherStop:
+				return
+			case <-ticker.C:
+				f.flushByTime()
+			}
+		}
+	}()
+}
+
+// stopFlusher stops goroutine of startFlusher and waits for its running flush
+func (f *filesystem) stopFlusher() {
+	if f.flusherStop == nil {
+		return
+	}
+
+	f.flusherOnce.Do(func() {
+		close(f.flusherStop)
+	})
+
+	f.flusherWg.Wait()
+}
+
+// flushByTime flushes buffered data into file and reports its error to config.FlushErrorHandler
+func (f *filesystem) flushByTime() {
+	f.writerMu.Lock()
+	err := f.flush()
+	f.writerMu.Unlock()
+
+	if err == nil {
+		return
+	}
+
+	// there isn't any caller to get the error, so it's reported to logger and flush error handler
+	err = f.newError("flush", errs.NoOffset, ErrFilesystemCouldNotFlush, err)
+	f.log.Error(err.Error(), "path", f.filePath)
+
+	if f.config.FlushErrorHandler != nil {
+		f.config.FlushErrorHandler(f.filePath, err)
+	}
+}
+
+// flushBeforeRead flushes buffered data, so readers can see data which has been written before, it gives up waiting for writer when ctx is done
+func (f *filesystem) flushBeforeRead(ctx context.Context, op string) error {
+	// writes which have been done before are visible by buffered counter, so readers don't wait for writer if nothing is buffered
+	if f.writer == nil || f.stats.buffered.Load() == 0 {
+		return nil
+	}
+
+	if err := f.lockWriter(ctx, op); err != nil {
+		return err
+	}
+	defer f.writerMu.Unlock()
+
+	if err := f.flush(); err != nil {
+		return f.newError("flush", errs.NoOffset, ErrFilesystemCouldNotFlush, err)
+	}
+
+	return nil
+}
+
+// lockWriter locks f.writerMu, it gives up when ctx is done and returns error of ctx wrapped with op
+func (f *filesystem) lockWriter(ctx context.Context, op string) error {
+	if err := f.writerMu.LockContext(ctx); err != nil {
+		return f.newError(op, errs.NoOffset, ErrFilesystemContextDone, err)
 	}
 
-	return f.readerState, nil
+	return nil
+}
+
+// lockOpenWriter is lockWriter which fails by f.writerErr if writer has been closed or broken
+func (f *filesystem) lockOpenWriter(ctx context.Context, op string) error {
+	if err := f.lockWriter(ctx, op); err != nil {
+		return err
+	}
+
+	if f.writerErr != nil {
+		f.writerMu.Unlock()
+		return f.writerErr
+	}
+
+	return nil
+}
+
+// resolveOffset converts offset & seek option of Write into offset of file (caller must hold f.writerMu)
+func (f *filesystem) resolveOffset(offset int64, seek int) (int64, error) {
+	var pos int64
+
+	switch seek {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.writerPos + offset
+	case io.SeekEnd:
+		size, err := f.writer.Size()
+		if err != nil {
+			return 0, f.newError("seek", offset, ErrFilesystemInvalidSeek, err)
+		}
+
+		if buffEnd := f.buffOffset + int64(len(f.buff)); len(f.buff) > 0 && buffEnd > size {
+			size = buffEnd
+		}
+
+		pos = size + offset
+	default:
+		return 0, ErrFilesystemInvalidSeek
+	}
+
+	if pos < 0 {
+		return 0, ErrFilesystemInvalidSeek
+	}
+
+	return pos, nil
+}
+
+// openReader opens new reader with its own read only file of filesystem
+func (f *filesystem) openReader() (reader.FileReader, error) {
+	rFile, err := f.openFileFunc(f.filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r, _ := reader.NewFileReader(rFile, f.config.IOMode)
+
+	return r, nil
+}
+
+// Stats returns a snapshot of counters of filesystem instance
+func (f *filesystem) Stats() Stats {
+	return f.stats.snapshot(f.config.MemoryRent)
+}
+
+// newError provides error of operation on file of filesystem which wraps its underlying cause, it's counted as failure of op
+func (f *filesystem) newError(op string, offset int64, err error, cause error) error {
+	f.stats.fail(op)
+
+	err = errs.New(op, f.filePath, offset, err, cause)
+
+	if f.config.Hooks.OnError != nil {
+		f.config.Hooks.OnError(f.filePath, op, err)
+	}
+
+	return err
+}
+
+// hookIO calls hook of I/O event with size of data and duration of operation since start, nil hook is skipped
+func (f *filesystem) hookIO(hook func(fPath string, bytes int, d time.Duration, err error), bytes int, start time.Time, err error) {
+	if hook != nil {
+		hook(f.filePath, bytes, time.Since(start), err)
+	}
+}
+
+// observe reports operation to logger if it has taken longer than config.SlowOperationThreshold
+func (f *filesystem) observe(op string, start time.Time) {
+	if f.config.SlowOperationThreshold <= 0 {
+		return
+	}
+
+	if d := time.Since(start); d >= f.config.SlowOperationThreshold {
+		f.log.Warn("package fs - slow operation", "op", op, "path", f.filePath, "duration", d)
+	}
 }
 
 // validateWriter will validate some parameters which related to writer before run any func of Filesystem interface
@@ -240,16 +1080,57 @@ func (f *filesystem) validateWriter() error {
 	return nil
 }
 
-// validateReader will validate some parameters which related to reader before run any func of Filesystem interface
+// validateReader will validate some parameters which related to reader before run any func of Filesystem interface (caller must hold f.readersMu)
 func (f *filesystem) validateReader() error {
 
-	if f.reader == nil {
+	if f.readerClosed {
 		return ErrFilesystemReaderNil
 	}
 
-	if f.readerState {
-		return ErrFilesystemReaderOccupying
+	return nil
+}
+
+// isReaderLimitReached reports whether filesystem can open another reader or not (caller must hold f.readersMu)
+func (f *filesystem) isReaderLimitReached() bool {
+	return f.config.ReaderLimit != 0 && uint32(len(f.readers)) >= f.config.ReaderLimit
+}
+
+// writerFlag returns flag which writer of filesystem opens its file by
+func writerFlag(perm cfgs.FSPerm) int {
+	if perm == cfgs.RW {
+		return os.O_RDWR | os.O_CREATE
 	}
 
-	return nil
+	return os.O_WRONLY | os.O_CREATE
+}
+
+// fileMode returns permission bits of files which are created by filesystem
+func fileMode(config fsConfig.FSConfiguration) os.FileMode {
+	if config.FileMode == 0 {
+		return fsConfig.DefaultFileMode
+	}
+
+	return config.FileMode
+}
+
+// containsReader reports whether readers contains r or not
+func containsReader(readers []reader.FileReader, r reader.FileReader) bool {
+	for _, fReader := range readers {
+		if fReader == r {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeReader returns readers without r
+func removeReader(readers []reader.FileReader, r reader.FileReader) []reader.FileReader {
+	for i, fReader := range readers {
+		if fReader == r {
+			return append(readers[:i], readers[i+1:]...)
+		}
+	}
+
+	return readers
 }
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package fs
+
+// SyncDirectory syncs entries of directory to disk, directories can't be synced on this platform so it's skipped
+func SyncDirectory(dirPath string, openFileFunc OpenFile) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package fs
+
+import "os"
+
+// SyncDirectory syncs entries of directory to disk, so creating or renaming a file in it survives a crash
+func SyncDirectory(dirPath string, openFileFunc OpenFile) error {
+	dir, err := openFileFunc(dirPath, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+
+	return dir.Close()
+}
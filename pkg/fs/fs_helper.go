@@ -2,6 +2,8 @@ package fs
 
 import (
 	"errors"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/file"
 	"io/fs"
 	"os"
 )
@@ -9,6 +11,9 @@ import (
 type Stat func(path string) (fs.FileInfo, error)
 type IsNotExist func(err error) bool
 type MkdirAll func(path string, mode fs.FileMode) error
+type OpenFile func(name string, flag int, perm fs.FileMode) (file.File, error)
+type Rename func(oldPath, newPath string) error
+type Remove func(path string) error
 
 var (
 	ErrFileIsNotExists         = errors.New("file path doesn't exist")
@@ -19,7 +24,7 @@ var (
 // IsFileExists checks file exist or not
 func IsFileExists(path string, statFunc Stat) error {
 	if _, err := statFunc(path); err != nil {
-		return ErrFileIsNotExists
+		return errs.New("stat", path, errs.NoOffset, ErrFileIsNotExists, err)
 	}
 	return nil
 }
@@ -28,7 +33,7 @@ func IsFileExists(path string, statFunc Stat) error {
 func IsDirectoryExists(path string, statFunc Stat, isNotExistFunc IsNotExist) error {
 	if _, err := statFunc(path); isNotExistFunc(err) {
 		if err != nil {
-			return ErrDirectoryIsNotExists
+			return errs.New("stat", path, errs.NoOffset, ErrDirectoryIsNotExists, err)
 		}
 	}
 	return nil
@@ -37,7 +42,7 @@ func IsDirectoryExists(path string, statFunc Stat, isNotExistFunc IsNotExist) er
 // CreateDirectory will create directory recursively
 func CreateDirectory(path string, mkdirAllFunc MkdirAll) error {
 	if err := mkdirAllFunc(path, os.ModePerm); err != nil {
-		return ErrCouldNotCreateDirectory
+		return errs.New("mkdir", path, errs.NoOffset, ErrCouldNotCreateDirectory, err)
 	}
 	return nil
 }
@@ -34,7 +34,7 @@ func TestIsFileExists_File_IsNotExists(t *testing.T) {
 
 	err := IsFileExists(someFilePath, mockFile.Stat)
 
-	assert.EqualError(t, err, ErrFileIsNotExists.Error())
+	assert.ErrorIs(t, err, ErrFileIsNotExists)
 }
 
 func TestIsDirectoryExists(t *testing.T) {
@@ -65,7 +65,7 @@ func TestIsDirectoryExists_DirectoryIsNotExists(t *testing.T) {
 
 	err := IsDirectoryExists(someDirPath, mockFile.Stat, mockFile.IsNotExist)
 
-	assert.EqualError(t, err, ErrDirectoryIsNotExists.Error())
+	assert.ErrorIs(t, err, ErrDirectoryIsNotExists)
 }
 
 func TestCreateDirectory(t *testing.T) {
@@ -91,5 +91,5 @@ func TestCreateDirectory_CouldNotCreateDirectory(t *testing.T) {
 
 	err := CreateDirectory(someDirPath, mockFile.MkdirAll)
 
-	assert.EqualError(t, err, ErrCouldNotCreateDirectory.Error())
+	assert.ErrorIs(t, err, ErrCouldNotCreateDirectory)
 }
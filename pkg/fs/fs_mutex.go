@@ -0,0 +1,35 @@
+package fs
+
+import "context"
+
+// mutex is a lock which can be waited for until a context is done, it should be made by newMutex
+type mutex chan struct{}
+
+// newMutex provides new unlocked mutex
+func newMutex() mutex {
+	return make(mutex, 1)
+}
+
+// Lock waits until mutex is locked
+func (m mutex) Lock() {
+	m <- struct{}{}
+}
+
+// LockContext waits until mutex is locked or ctx is done, mutex is not locked if it returns error of ctx
+func (m mutex) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case m <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock unlocks mutex
+func (m mutex) Unlock() {
+	<-m
+}
@@ -0,0 +1,48 @@
+package fs
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMutex_LockContext(t *testing.T) {
+	m := newMutex()
+
+	err := m.LockContext(context.Background())
+
+	assert.Nil(t, err)
+
+	m.Unlock()
+	m.Lock()
+	m.Unlock()
+}
+
+func TestMutex_LockContext_ContextIsDone(t *testing.T) {
+	m := newMutex()
+	m.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := m.LockContext(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// mutex is still held by the first locker
+	m.Unlock()
+	assert.Nil(t, m.LockContext(context.Background()))
+}
+
+func TestMutex_LockContext_ContextIsCanceled(t *testing.T) {
+	m := newMutex()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.LockContext(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, m.LockContext(context.Background()))
+}
@@ -0,0 +1,211 @@
+package fs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are upper bounds of buckets of latency histograms, latencies beyond the largest one are counted in an extra bucket
+var LatencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Histogram counts latencies by LatencyBuckets, Counts[i] is number of latencies in (LatencyBuckets[i-1], LatencyBuckets[i]]
+// the last count is number of latencies beyond the largest bucket
+type Histogram struct {
+	Counts [len(LatencyBuckets) + 1]uint64
+}
+
+// Observe counts latency d in its bucket, it's not safe for concurrent use
+func (h *Histogram) Observe(d time.Duration) {
+	h.Counts[latencyBucket(d)]++
+}
+
+// Count returns number of counted latencies
+func (h Histogram) Count() uint64 {
+	var count uint64
+
+	for _, n := range h.Counts {
+		count += n
+	}
+
+	return count
+}
+
+// Add returns sum of both histograms
+func (h Histogram) Add(o Histogram) Histogram {
+	for i, n := range o.Counts {
+		h.Counts[i] += n
+	}
+
+	return h
+}
+
+// latencyBucket returns index of bucket of latency d in Histogram.Counts
+func latencyBucket(d time.Duration) int {
+	for i, bound := range LatencyBuckets {
+		if d <= bound {
+			return i
+		}
+	}
+
+	return len(LatencyBuckets)
+}
+
+/*
+* Stats is a snapshot of counters of filesystem instance
+* Writes & BytesWritten: successful writes and their bytes (including buffered ones)
+* Reads & BytesRead: successful reads and their bytes
+* Flushes: number of flushes of memory rent into file
+* FlushDuration: total duration of flushes, FlushDuration / Flushes is the average latency
+* MaxFlushDuration: the slowest flush
+* FlushLatency: distribution of latencies of flushes by LatencyBuckets
+* Buffered: bytes which are kept in memory rent and have not been flushed yet
+* MemoryRent: capacity of memory rent
+* Errors: number of failed operations by kind of operation (open, write, read, flush, sync, seek, close)
+ */
+type Stats struct {
+	Writes           uint64
+	BytesWritten     uint64
+	Reads            uint64
+	BytesRead        uint64
+	Flushes          uint64
+	FlushDuration    time.Duration
+	MaxFlushDuration time.Duration
+	FlushLatency     Histogram
+	Buffered         uint64
+	MemoryRent       uint64
+	Errors           map[string]uint64
+}
+
+// BufferOccupancy returns ratio of buffered data to memory rent
+func (s Stats) BufferOccupancy() float64 {
+	if s.MemoryRent == 0 {
+		return 0
+	}
+
+	return float64(s.Buffered) / float64(s.MemoryRent)
+}
+
+// Add returns sum of both stats, MaxFlushDuration is the slowest flush of both
+func (s Stats) Add(o Stats) Stats {
+	sum := Stats{
+		Writes:           s.Writes + o.Writes,
+		BytesWritten:     s.BytesWritten + o.BytesWritten,
+		Reads:            s.Reads + o.Reads,
+		BytesRead:        s.BytesRead + o.BytesRead,
+		Flushes:          s.Flushes + o.Flushes,
+		FlushDuration:    s.FlushDuration + o.FlushDuration,
+		MaxFlushDuration: s.MaxFlushDuration,
+		FlushLatency:     s.FlushLatency.Add(o.FlushLatency),
+		Buffered:         s.Buffered + o.Buffered,
+		MemoryRent:       s.MemoryRent + o.MemoryRent,
+		Errors:           make(map[string]uint64, len(s.Errors)),
+	}
+
+	if o.MaxFlushDuration > sum.MaxFlushDuration {
+		sum.MaxFlushDuration = o.MaxFlushDuration
+	}
+
+	for op, n := range s.Errors {
+		sum.Errors[op] += n
+	}
+
+	for op, n := range o.Errors {
+		sum.Errors[op] += n
+	}
+
+	return sum
+}
+
+// stats keeps counters of filesystem instance, they are updated without holding locks of filesystem
+type stats struct {
+	writes           atomic.Uint64
+	bytesWritten     atomic.Uint64
+	reads            atomic.Uint64
+	bytesRead        atomic.Uint64
+	flushes          atomic.Uint64
+	flushDuration    atomic.Int64
+	maxFlushDuration atomic.Int64
+	flushLatency     [len(LatencyBuckets) + 1]atomic.Uint64
+	buffered         atomic.Uint64
+	errorsMu         sync.Mutex
+	errors           map[string]uint64
+}
+
+// write counts a successful write
+func (s *stats) write(n int) {
+	s.writes.Add(1)
+	s.bytesWritten.Add(uint64(n))
+}
+
+// read counts a successful read
+func (s *stats) read(n int) {
+	s.reads.Add(1)
+	s.bytesRead.Add(uint64(n))
+}
+
+// flush counts a successful flush and its latency
+func (s *stats) flush(d time.Duration) {
+	s.flushes.Add(1)
+	s.flushDuration.Add(int64(d))
+	s.flushLatency[latencyBucket(d)].Add(1)
+
+	for {
+		max := s.maxFlushDuration.Load()
+		if int64(d) <= max || s.maxFlushDuration.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// fail counts a failed operation
+func (s *stats) fail(op string) {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+
+	if s.errors == nil {
+		s.errors = make(map[string]uint64)
+	}
+
+	s.errors[op]++
+}
+
+// snapshot returns Stats of counters
+func (s *stats) snapshot(memoryRent uint64) Stats {
+	s.errorsMu.Lock()
+	errCounts := make(map[string]uint64, len(s.errors))
+	for op, n := range s.errors {
+		errCounts[op] = n
+	}
+	s.errorsMu.Unlock()
+
+	var flushLatency Histogram
+	for i := range s.flushLatency {
+		flushLatency.Counts[i] = s.flushLatency[i].Load()
+	}
+
+	return Stats{
+		Writes:           s.writes.Load(),
+		BytesWritten:     s.bytesWritten.Load(),
+		Reads:            s.reads.Load(),
+		BytesRead:        s.bytesRead.Load(),
+		Flushes:          s.flushes.Load(),
+		FlushDuration:    time.Duration(s.flushDuration.Load()),
+		MaxFlushDuration: time.Duration(s.maxFlushDuration.Load()),
+		FlushLatency:     flushLatency,
+		Buffered:         s.buffered.Load(),
+		MemoryRent:       memoryRent,
+		Errors:           errCounts,
+	}
+}
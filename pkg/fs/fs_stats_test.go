@@ -0,0 +1,154 @@
+package fs
+
+import (
+	mockfile "github.com/amirvalhalla/fspool/mocks/file"
+	cfgs2 "github.com/amirvalhalla/fspool/pkg/cfgs"
+	cfgs "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFilesystem_Stats(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2, 3}, int64(0)).Return(3, nil).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(1)).Return(2, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.MemoryRent = 4
+	fsConfig.FlushSize = 4
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{1}, 0, io.SeekStart)
+	_ = f.Write([]byte{2, 3}, 0, io.SeekCurrent)
+
+	stats := f.Stats()
+
+	assert.Equal(t, uint64(2), stats.Writes)
+	assert.Equal(t, uint64(3), stats.BytesWritten)
+	assert.Equal(t, uint64(3), stats.Buffered)
+	assert.Equal(t, uint64(4), stats.MemoryRent)
+	assert.Equal(t, 0.75, stats.BufferOccupancy())
+	assert.Equal(t, uint64(0), stats.Flushes)
+
+	_, _ = f.ReadData(1, 2, io.SeekStart)
+
+	stats = f.Stats()
+
+	assert.Equal(t, uint64(1), stats.Flushes)
+	assert.Equal(t, stats.FlushDuration, stats.MaxFlushDuration)
+	assert.Equal(t, uint64(1), stats.FlushLatency.Count())
+	assert.Equal(t, uint64(0), stats.Buffered)
+	assert.Equal(t, uint64(1), stats.Reads)
+	assert.Equal(t, uint64(2), stats.BytesRead)
+	assert.Empty(t, stats.Errors)
+}
+
+func TestFilesystem_Stats_Errors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, syscall.ENOSPC).Times(2)
+	mockFile.EXPECT().Sync().Return(syscall.EIO).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushSize = 0
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	_ = f.Sync()
+
+	stats := f.Stats()
+
+	// failed writes are not kept in memory rent, so Sync doesn't flush them again
+	assert.Equal(t, map[string]uint64{"write": 2, "sync": 1}, stats.Errors)
+	assert.Equal(t, uint64(0), stats.Writes)
+	assert.Equal(t, uint64(0), stats.Buffered)
+}
+
+func TestStats_Add(t *testing.T) {
+	s1 := Stats{
+		Writes:           1,
+		BytesWritten:     10,
+		Flushes:          2,
+		FlushDuration:    3 * time.Millisecond,
+		MaxFlushDuration: 2 * time.Millisecond,
+		FlushLatency:     Histogram{Counts: [11]uint64{2: 1, 3: 1}},
+		Buffered:         5,
+		MemoryRent:       20,
+		Errors:           map[string]uint64{"write": 1},
+	}
+	s2 := Stats{
+		Reads:            3,
+		BytesRead:        30,
+		Flushes:          1,
+		FlushDuration:    5 * time.Millisecond,
+		MaxFlushDuration: 5 * time.Millisecond,
+		FlushLatency:     Histogram{Counts: [11]uint64{3: 1}},
+		Buffered:         5,
+		MemoryRent:       20,
+		Errors:           map[string]uint64{"write": 2, "read": 1},
+	}
+
+	sum := s1.Add(s2)
+
+	assert.Equal(t, Stats{
+		Writes:           1,
+		BytesWritten:     10,
+		Reads:            3,
+		BytesRead:        30,
+		Flushes:          3,
+		FlushDuration:    8 * time.Millisecond,
+		MaxFlushDuration: 5 * time.Millisecond,
+		FlushLatency:     Histogram{Counts: [11]uint64{2: 1, 3: 2}},
+		Buffered:         10,
+		MemoryRent:       40,
+		Errors:           map[string]uint64{"write": 3, "read": 1},
+	}, sum)
+	assert.Equal(t, 0.25, sum.BufferOccupancy())
+	assert.Equal(t, map[string]uint64{"write": 1}, s1.Errors)
+}
+
+func TestHistogram_Observe(t *testing.T) {
+	var h Histogram
+
+	h.Observe(0)
+	h.Observe(100 * time.Microsecond)
+	h.Observe(2 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	assert.Equal(t, [11]uint64{0: 2, 3: 1, 10: 1}, h.Counts)
+	assert.Equal(t, uint64(4), h.Count())
+}
+
+func TestStats_BufferOccupancy_MemoryRentIsZero(t *testing.T) {
+	assert.Equal(t, float64(0), Stats{Buffered: 1}.BufferOccupancy())
+}
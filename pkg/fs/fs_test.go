@@ -1,15 +1,24 @@
 package fs
 
 import (
+	"context"
+	"errors"
 	mockfile "github.com/amirvalhalla/fspool/mocks/file"
 	cfgs2 "github.com/amirvalhalla/fspool/pkg/cfgs"
 	cfgs "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/reader"
+	"github.com/amirvalhalla/fspool/pkg/writer"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestNewFilesystem(t *testing.T) {
@@ -21,11 +30,13 @@ func TestNewFilesystem(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	assert.Nil(t, err)
 }
@@ -39,13 +50,14 @@ func TestNewFilesystem_With_ROnly_Perm(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
 	fsConfig.Perm = cfgs2.ROnly
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	assert.Nil(t, err)
 }
@@ -59,13 +71,14 @@ func TestNewFilesystem_With_WOnly_Perm(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
 	fsConfig.Perm = cfgs2.WOnly
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	assert.Nil(t, err)
 }
@@ -74,15 +87,14 @@ func TestNewFilesystem_EmptyFilePath(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	_, err := NewFilesystem("", fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem("", fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	assert.EqualError(t, err, ErrFilesystemFilepathIsEmpty.Error())
+	assert.ErrorIs(t, err, ErrFilesystemFilepathIsEmpty)
 }
 
 func TestNewFilesystem_ConflictInMemoryRentSizeWithFlushSize(t *testing.T) {
@@ -91,7 +103,6 @@ func TestNewFilesystem_ConflictInMemoryRentSizeWithFlushSize(t *testing.T) {
 
 	someFilePath := filepath.Join("/test", "/test.txt")
 
-	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 
 	fsConfig := cfgs.FSConfiguration{}
@@ -99,9 +110,9 @@ func TestNewFilesystem_ConflictInMemoryRentSizeWithFlushSize(t *testing.T) {
 
 	fsConfig.FlushSize = 60 * 1024 * 1024
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	assert.EqualError(t, err, ErrFilesystemMemoryRentConflictWithFlushSize.Error())
+	assert.ErrorIs(t, err, ErrFilesystemMemoryRentConflictWithFlushSize)
 }
 
 func TestNewFilesystem_FilePathIsNotExists_With_ReadOnly_Permission(t *testing.T) {
@@ -110,7 +121,6 @@ func TestNewFilesystem_FilePathIsNotExists_With_ReadOnly_Permission(t *testing.T
 
 	someFilePath := filepath.Join("/test", "/test.txt")
 
-	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, ErrFileIsNotExists).Times(1)
 
@@ -118,9 +128,9 @@ func TestNewFilesystem_FilePathIsNotExists_With_ReadOnly_Permission(t *testing.T
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	assert.EqualError(t, err, ErrFileIsNotExists.Error())
+	assert.ErrorIs(t, err, ErrFileIsNotExists)
 }
 
 func TestNewFilesystem_FilePathIsNotExists_CouldNotCreateDirectory(t *testing.T) {
@@ -130,7 +140,6 @@ func TestNewFilesystem_FilePathIsNotExists_CouldNotCreateDirectory(t *testing.T)
 	someDirPath := filepath.Join("/test")
 	someFilePath := filepath.Join(someDirPath, "/test.txt")
 
-	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	//mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
 
@@ -141,9 +150,9 @@ func TestNewFilesystem_FilePathIsNotExists_CouldNotCreateDirectory(t *testing.T)
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	_, err := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	assert.EqualError(t, err, ErrCouldNotCreateDirectory.Error())
+	assert.ErrorIs(t, err, ErrCouldNotCreateDirectory)
 }
 
 func TestFilesystem_Write(t *testing.T) {
@@ -153,16 +162,18 @@ func TestFilesystem_Write(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Write([]byte{2}).Return(0, nil).Times(1)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, nil).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
+	fsConfig.FlushSize = 1
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Write([]byte{2}, 0, io.SeekStart)
 
@@ -179,16 +190,17 @@ func TestFilesystem_Write_Writer_nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Write([]byte{2}, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFilesystemWriterNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
 }
 
 func TestFilesystem_Write_Writer_CouldNotWrite(t *testing.T) {
@@ -198,20 +210,22 @@ func TestFilesystem_Write_Writer_CouldNotWrite(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Write([]byte{2}).Return(0, ErrFilesystemCouldNotWrite).Times(1)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, ErrFilesystemCouldNotWrite).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
+	fsConfig.FlushSize = 1
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Write([]byte{2}, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFilesystemCouldNotWrite.Error())
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotWrite)
 }
 
 func TestFilesystem_Sync(t *testing.T) {
@@ -225,11 +239,13 @@ func TestFilesystem_Sync(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Sync()
 
@@ -246,16 +262,17 @@ func TestFilesystem_Sync_Writer_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Sync()
 
-	assert.EqualError(t, err, ErrFilesystemWriterNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
 }
 
 func TestFilesystem_Sync_CouldNotSync(t *testing.T) {
@@ -269,15 +286,17 @@ func TestFilesystem_Sync_CouldNotSync(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.Sync()
 
-	assert.EqualError(t, err, ErrFilesystemWriterCouldNotSync.Error())
+	assert.ErrorIs(t, err, ErrFilesystemWriterCouldNotSync)
 }
 
 func TestFilesystem_GetWriterId(t *testing.T) {
@@ -290,12 +309,13 @@ func TestFilesystem_GetWriterId(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetWriterId()
 
@@ -312,16 +332,17 @@ func TestFilesystem_GetWriterId_Writer_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetWriterId()
 
-	assert.EqualError(t, err, ErrFilesystemWriterNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
 }
 
 func TestFilesystem_CloseWriter(t *testing.T) {
@@ -335,17 +356,55 @@ func TestFilesystem_CloseWriter(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseWriter()
 
 	assert.Nil(t, err)
 }
 
+func TestFilesystem_CloseWriter_WriterIsClosed(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.CloseWriter())
+
+	// writes are refused instead of being buffered for a closed writer
+	assert.ErrorIs(t, f.Write([]byte{1}, 0, io.SeekStart), ErrFilesystemWriterNil)
+	assert.ErrorIs(t, f.Sync(), ErrFilesystemWriterNil)
+	assert.ErrorIs(t, f.CloseWriter(), ErrFilesystemWriterNil)
+
+	_, err := f.Append([]byte{1})
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
+
+	// closed writer is not closed again
+	assert.Nil(t, f.Close())
+}
+
 func TestFilesystem_CloseWriter_nil(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -356,16 +415,17 @@ func TestFilesystem_CloseWriter_nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseWriter()
 
-	assert.EqualError(t, err, ErrFilesystemWriterNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
 }
 
 func TestFilesystem_CloseWriter_CouldNotClose(t *testing.T) {
@@ -379,15 +439,17 @@ func TestFilesystem_CloseWriter_CouldNotClose(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseWriter()
 
-	assert.EqualError(t, err, ErrFilesystemCouldNotCloseWriter.Error())
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotCloseWriter)
 }
 
 func TestFilesystem_ReadData(t *testing.T) {
@@ -397,18 +459,19 @@ func TestFilesystem_ReadData(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	_, err := f.ReadData(0, 0, io.SeekStart)
+	_, err := f.ReadData(0, 1, io.SeekStart)
 
 	assert.Nil(t, err)
 }
@@ -423,16 +486,17 @@ func TestFilesystem_ReadData_Reader_nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.ReadData(0, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFilesystemReaderNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
 }
 
 func TestFilesystem_ReadData_Reader_Occupying(t *testing.T) {
@@ -442,33 +506,30 @@ func TestFilesystem_ReadData_Reader_Occupying(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).AnyTimes()
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).AnyTimes()
+	mockFile.EXPECT().ReadAt(gomock.Any(), int64(0)).Return(1, nil).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	go func() {
-		for {
-			f.ReadData(0, 0, io.SeekStart)
-		}
-	}()
+	// the only reader is held, so ReadData can't get any reader
+	r, _ := f.AcquireReader()
 
-	var err error
+	_, err := f.ReadData(0, 1, io.SeekStart)
 
-	for {
-		_, err = f.ReadData(0, 0, io.SeekStart)
-		if err != nil {
-			break
-		}
-	}
+	assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+
+	_ = f.ReleaseReader(r)
 
-	assert.EqualError(t, err, ErrFilesystemReaderOccupying.Error())
+	_, err = f.ReadData(0, 1, io.SeekStart)
+
+	assert.Nil(t, err)
 }
 
 func TestFilesystem_ReadData_CouldNotReadData(t *testing.T) {
@@ -478,20 +539,21 @@ func TestFilesystem_ReadData_CouldNotReadData(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, ErrFilesystemCouldNotReadData).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(0, ErrFilesystemCouldNotReadData).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	_, err := f.ReadData(0, 0, io.SeekStart)
+	_, err := f.ReadData(0, 1, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFilesystemCouldNotReadData.Error())
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotReadData)
 }
 
 func TestFilesystem_ReadAllData(t *testing.T) {
@@ -503,18 +565,20 @@ func TestFilesystem_ReadAllData(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
 
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
 	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
 
-	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(1)).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.ReadAllData()
 
@@ -531,15 +595,16 @@ func TestFilesystem_ReadAllData_Reader_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 	_, err := f.ReadAllData()
 
-	assert.EqualError(t, err, ErrFilesystemReaderNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
 }
 
 func TestFilesystem_ReadAllData_Reader_Occupying(t *testing.T) {
@@ -551,34 +616,32 @@ func TestFilesystem_ReadAllData_Reader_Occupying(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
 
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).AnyTimes()
-	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).AnyTimes()
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
 
-	mockFileInfo.EXPECT().Size().Return(int64(0)).AnyTimes()
+	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	var err error
-	go func() {
-		for {
-			f.ReadAllData()
-		}
-	}()
+	// the only reader is held, so ReadAllData can't get any reader
+	r, _ := f.AcquireReader()
 
-	for {
-		_, err = f.ReadAllData()
-		if err != nil {
-			break
-		}
-	}
+	_, err := f.ReadAllData()
 
-	assert.EqualError(t, err, ErrFilesystemReaderOccupying.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+
+	_ = f.ReleaseReader(r)
+
+	_, err = f.ReadAllData()
+
+	assert.Nil(t, err)
 }
 
 func TestFilesystem_ReadAllData_CouldNotReadAllData(t *testing.T) {
@@ -590,22 +653,24 @@ func TestFilesystem_ReadAllData_CouldNotReadAllData(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
 
-	mockFile.EXPECT().Read([]byte{}).Return(0, ErrFilesystemCouldNotReadAllData).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(0, ErrFilesystemCouldNotReadAllData).Times(1)
 	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
 
-	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(1)).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.ReadAllData()
 
-	assert.EqualError(t, err, ErrFilesystemCouldNotReadAllData.Error())
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotReadAllData)
 }
 
 func TestFilesystem_GetReaderId(t *testing.T) {
@@ -618,12 +683,13 @@ func TestFilesystem_GetReaderId(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetReaderId()
 
@@ -640,16 +706,17 @@ func TestFilesystem_GetReaderId_Reader_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetReaderId()
 
-	assert.EqualError(t, err, ErrFilesystemReaderNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
 }
 
 func TestFilesystem_CloseReader(t *testing.T) {
@@ -663,11 +730,13 @@ func TestFilesystem_CloseReader(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseReader()
 
@@ -684,16 +753,17 @@ func TestFilesystem_CloseReader_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseReader()
 
-	assert.EqualError(t, err, ErrFilesystemReaderNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
 }
 
 func TestFilesystem_CloseReader_Occupying(t *testing.T) {
@@ -703,37 +773,58 @@ func TestFilesystem_CloseReader_Occupying(t *testing.T) {
 	someFilePath := filepath.Join("/test", "/test.txt")
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, _ := f.AcquireReader()
+
+	err := f.CloseReader()
+
+	assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+
+	_ = f.ReleaseReader(r)
+
+	assert.Nil(t, f.CloseReader())
+}
+
+func TestFilesystem_CloseReader_ForgetsReaders(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
 
-	mockFile.EXPECT().Close().Return(nil).AnyTimes()
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).AnyTimes()
-	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).AnyTimes()
+	someFilePath := filepath.Join("/test", "/test.txt")
 
-	mockFileInfo.EXPECT().Size().Return(int64(0)).AnyTimes()
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
-	var err error
-	go func() {
-		for {
-			f.ReadAllData()
-		}
-	}()
+	assert.Nil(t, f.CloseReader())
 
-	for {
-		err = f.CloseReader()
-		if err != nil {
-			break
-		}
-	}
+	// closed readers must not be handed out anymore
+	_, err := f.ReadData(0, 1, io.SeekStart)
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
+
+	_, err = f.GetReaderState()
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
 
-	assert.EqualError(t, err, ErrFilesystemReaderOccupying.Error())
+	assert.ErrorIs(t, f.CloseReader(), ErrFilesystemReaderNil)
 }
 
 func TestFilesystem_CloseReader_CouldNotClose(t *testing.T) {
@@ -747,15 +838,17 @@ func TestFilesystem_CloseReader_CouldNotClose(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	err := f.CloseReader()
 
-	assert.EqualError(t, err, ErrFilesystemCouldNotCloseReader.Error())
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotCloseReader)
 }
 
 func TestFilesystem_GetReaderState(t *testing.T) {
@@ -768,11 +861,13 @@ func TestFilesystem_GetReaderState(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetReaderState()
 
@@ -789,14 +884,2092 @@ func TestFilesystem_GetReaderState_Nil(t *testing.T) {
 
 	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
 	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
 
 	fsConfig := cfgs.FSConfiguration{}
 	fsConfig.New()
 	fsConfig.Perm = cfgs2.WOnly
 
-	f, _ := NewFilesystem(someFilePath, fsConfig, mockFile, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll)
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
 
 	_, err := f.GetReaderState()
 
-	assert.EqualError(t, err, ErrFilesystemReaderNil.Error())
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
+}
+
+func TestFilesystem_Close(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Close()
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Close_With_ROnly_Perm(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Close()
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Close_CouldNotClose(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(ErrFilesystemCouldNotClose).Times(1)
+
+	// reader is closed even if closing writer fails
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Close()
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotClose)
+}
+
+func TestFilesystem_Close_CouldNotFlush(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	// writer and reader are closed even if flushing fails
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(0)).Return(0, syscall.EIO).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{1}, 0, io.SeekStart)
+
+	err := f.Close()
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotFlush)
+	assert.ErrorIs(t, err, syscall.EIO)
+	assert.Equal(t, uint64(0), f.Stats().Buffered)
+
+	// closing again doesn't touch closed files
+	assert.Nil(t, f.Close())
+}
+
+func TestFilesystem_AcquireReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, err := f.AcquireReader()
+
+	assert.Nil(t, err)
+	assert.NotNil(t, r)
+}
+
+func TestFilesystem_AcquireReader_OpensNewReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.ReaderLimit = 2
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r1, _ := f.AcquireReader()
+	r2, err := f.AcquireReader()
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, r1.GetId(), r2.GetId())
+
+	_, err = r2.ReadData(0, 1, io.SeekStart)
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_AcquireReader_Unlimited(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(9)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.ReaderLimit = 0
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	for i := 0; i < 10; i++ {
+		_, err := f.AcquireReader()
+		assert.Nil(t, err)
+	}
+}
+
+func TestFilesystem_AcquireReader_Reader_Occupying(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.ReaderLimit = 2
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, _ = f.AcquireReader()
+	_, _ = f.AcquireReader()
+	_, err := f.AcquireReader()
+
+	assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+
+	state, _ := f.GetReaderState()
+
+	assert.True(t, state)
+
+	_, err = f.ReadData(0, 0, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+}
+
+func TestFilesystem_AcquireReader_CouldNotOpenReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(nil, ErrFileIsNotExists).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.ReaderLimit = 2
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, _ = f.AcquireReader()
+	_, err := f.AcquireReader()
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotOpenReader)
+}
+
+func TestFilesystem_AcquireReader_Reader_Nil(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, err := f.AcquireReader()
+
+	assert.ErrorIs(t, err, ErrFilesystemReaderNil)
+}
+
+func TestFilesystem_ReleaseReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, _ := f.AcquireReader()
+	err := f.ReleaseReader(r)
+
+	assert.Nil(t, err)
+
+	state, _ := f.GetReaderState()
+
+	assert.False(t, state)
+}
+
+func TestFilesystem_ReleaseReader_IsNotAcquired(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, _ := f.AcquireReader()
+	_ = f.ReleaseReader(r)
+	err := f.ReleaseReader(r)
+
+	assert.ErrorIs(t, err, ErrFilesystemReaderIsNotAcquired)
+}
+
+func TestFilesystem_Close_With_Multiple_Readers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().Close().Return(nil).Times(2)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(2)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.ReaderLimit = 2
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, _ = f.AcquireReader()
+	_, _ = f.AcquireReader()
+
+	err := f.Close()
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Write_KeepsDataInMemoryRent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{2}, 0, io.SeekStart)
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Write_FlushBySize(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2, 3, 4}, int64(0)).Return(4, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.MemoryRent = 8
+	fsConfig.FlushSize = 4
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Write([]byte{1, 2}, 0, io.SeekStart))
+	assert.Nil(t, f.Write([]byte{3, 4}, 0, io.SeekCurrent))
+}
+
+func TestFilesystem_Write_FlushesBeforeNonContiguousWrite(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2}, int64(0)).Return(2, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Write([]byte{1, 2}, 0, io.SeekStart))
+	assert.Nil(t, f.Write([]byte{3, 4}, 10, io.SeekStart))
+}
+
+func TestFilesystem_Write_BiggerThanMemoryRent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2, 3}, int64(0)).Return(3, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.MemoryRent = 2
+	fsConfig.FlushSize = 2
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{1, 2, 3}, 0, io.SeekStart)
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Write_SeekEnd(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(10)).Times(1)
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(10)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushSize = 1
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{1}, 0, io.SeekEnd)
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Write_InvalidSeek(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{1}, -1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFilesystemInvalidSeek)
+}
+
+func TestFilesystem_Sync_FlushesMemoryRent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	gomock.InOrder(
+		mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(1, nil).Times(1),
+		mockFile.EXPECT().Sync().Return(nil).Times(1),
+	)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	err := f.Sync()
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Sync_CouldNotFlush(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, ErrFilesystemCouldNotWrite).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	err := f.Sync()
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotFlush)
+}
+
+func TestFilesystem_CloseWriter_FlushesMemoryRent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	gomock.InOrder(
+		mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(1, nil).Times(1),
+		mockFile.EXPECT().Close().Return(nil).Times(1),
+	)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	err := f.CloseWriter()
+
+	assert.Nil(t, err)
+}
+
+func TestNewFilesystem_FlushDurationIsZero(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushType = cfgs2.FlushByTime
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.ErrorIs(t, err, ErrFilesystemFlushDurationIsZero)
+}
+
+func TestFilesystem_Write_FlushByTime(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	flushed := make(chan struct{})
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		close(flushed)
+		return len(p), nil
+	}).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushType = cfgs2.FlushByTime
+	fsConfig.FlushDuration = 10 * time.Millisecond
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Write([]byte{2}, 0, io.SeekStart))
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("buffered data should be flushed by timer")
+	}
+
+	assert.Nil(t, f.CloseWriter())
+}
+
+func TestFilesystem_Write_FlushByTime_StopsOnCloseWriter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushType = cfgs2.FlushByTime
+	fsConfig.FlushDuration = time.Millisecond
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.CloseWriter())
+
+	// writing after closing writer must not be flushed by timer anymore
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestFilesystem_Write_FlushByTime_FlushErrorHandler(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	flushErrs := make(chan error, 1)
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, ErrFilesystemCouldNotWrite).MinTimes(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushType = cfgs2.FlushByTime
+	fsConfig.FlushDuration = 10 * time.Millisecond
+	fsConfig.FlushErrorHandler = func(fPath string, err error) {
+		select {
+		case flushErrs <- err:
+		default:
+		}
+	}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+	defer f.(*filesystem).stopFlusher()
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+
+	select {
+	case err := <-flushErrs:
+		assert.ErrorIs(t, err, ErrFilesystemCouldNotFlush)
+	case <-time.After(time.Second):
+		t.Fatal("flush error should be reported to flush error handler")
+	}
+}
+
+func TestNewFilesystem_ConflictInMemoryRentSizeWithFlushPolicySize(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushPolicy = cfgs2.FlushPolicy{Size: 60 * cfgs.MB}
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.ErrorIs(t, err, ErrFilesystemMemoryRentConflictWithFlushSize)
+}
+
+func TestFilesystem_Write_FlushPolicy_Writes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2, 3}, int64(0)).Return(3, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushPolicy = cfgs2.FlushPolicy{Size: 1024, Writes: 3}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Write([]byte{1}, 0, io.SeekStart))
+	assert.Nil(t, f.Write([]byte{2}, 0, io.SeekCurrent))
+	assert.Nil(t, f.Write([]byte{3}, 0, io.SeekCurrent))
+}
+
+func TestFilesystem_Write_FlushPolicy_SizeOrAge(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	flushed := make(chan struct{}, 2)
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt(gomock.Any(), gomock.Any()).DoAndReturn(func(p []byte, off int64) (int, error) {
+		flushed <- struct{}{}
+		return len(p), nil
+	}).Times(2)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushPolicy = cfgs2.FlushPolicy{Size: 4, Age: 20 * time.Millisecond}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	// reaching size flushes immediately
+	assert.Nil(t, f.Write([]byte{1, 2, 3, 4}, 0, io.SeekStart))
+	assert.Len(t, flushed, 1)
+	<-flushed
+
+	// reaching age flushes by timer
+	assert.Nil(t, f.Write([]byte{5}, 0, io.SeekCurrent))
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("buffered data should be flushed when it gets older than age of flush policy")
+	}
+
+	assert.Nil(t, f.CloseWriter())
+}
+
+func TestFilesystem_Flush(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	err := f.Flush()
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_Flush_Writer_Nil(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Flush()
+
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
+}
+
+func TestFilesystem_Flush_CouldNotFlush(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, ErrFilesystemCouldNotWrite).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	err := f.Flush()
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotFlush)
+}
+
+func TestNewFilesystem_CreatesDirectoryBeforeOpeningFile(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someDirPath := filepath.Join("/test")
+	someFilePath := filepath.Join(someDirPath, "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, ErrFileIsNotExists).Times(2)
+	mockFileHelper.EXPECT().IsNotExist(ErrFileIsNotExists).Return(true).Times(1)
+	gomock.InOrder(
+		mockFileHelper.EXPECT().MkdirAll(someDirPath, os.ModePerm).Return(nil).Times(1),
+		mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, os.FileMode(0600)).Return(mockFile, nil).Times(1),
+	)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FileMode = 0600
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, err)
+}
+
+func TestNewFilesystem_DefaultFileMode(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FileMode = 0
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, err)
+}
+
+func TestNewFilesystem_CouldNotOpenFile(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(nil, ErrFileIsNotExists).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotOpenFile)
+}
+
+func TestNewFilesystem_With_RW_Perm_OpensSeparateFiles(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockWriterFile := mockfile.NewMockFile(mockCtrl)
+	mockWriterFile.EXPECT().WriteAt([]byte{2}, int64(4)).Return(1, nil).Times(1)
+	mockWriterFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockWriterFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.FlushSize = 1
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Write([]byte{2}, 4, io.SeekStart))
+
+	_, err := f.ReadData(0, 1, io.SeekStart)
+	assert.Nil(t, err)
+
+	// closing writer must not close file of reader
+	assert.Nil(t, f.CloseWriter())
+}
+
+func TestNewFilesystem_CouldNotOpenReader_ClosesWriter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(nil, ErrFileIsNotExists).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	_, err := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotOpenFile)
+}
+
+func TestFilesystem_ReadData_EOF(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		p[0] = 1
+		return 1, io.EOF
+	}).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	rawData, err := f.ReadData(0, 2, io.SeekStart)
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, []byte{1}, rawData)
+}
+
+func TestFilesystem_Write_CouldNotWrite_KeepsCause(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(4)).Return(0, syscall.ENOSPC).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushSize = 1
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{2}, 4, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotWrite)
+	assert.ErrorIs(t, err, writer.ErrFileWriterCouldNotWrite)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+
+	var fsErr *errs.Error
+	assert.ErrorAs(t, err, &fsErr)
+	assert.Equal(t, someFilePath, fsErr.Path)
+	assert.Equal(t, int64(4), fsErr.Offset)
+}
+
+// recordLogger keeps messages which have been logged by level
+type recordLogger struct {
+	mu   sync.Mutex
+	msgs map[string][]string
+}
+
+func newRecordLogger() *recordLogger {
+	return &recordLogger{msgs: make(map[string][]string)}
+}
+
+func (l *recordLogger) record(level string, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs[level] = append(l.msgs[level], msg)
+}
+
+func (l *recordLogger) get(level string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.msgs[level]...)
+}
+
+func (l *recordLogger) Debug(msg string, _ ...any) { l.record("debug", msg) }
+func (l *recordLogger) Info(msg string, _ ...any)  { l.record("info", msg) }
+func (l *recordLogger) Warn(msg string, _ ...any)  { l.record("warn", msg) }
+func (l *recordLogger) Error(msg string, _ ...any) { l.record("error", msg) }
+
+func TestFilesystem_Logger_Flush(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	log := newRecordLogger()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.Logger = log
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+	assert.Nil(t, f.Flush())
+
+	assert.Len(t, log.get("debug"), 1)
+	assert.Empty(t, log.get("error"))
+}
+
+func TestFilesystem_Logger_FlushByTime_Error(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	log := newRecordLogger()
+	flushed := make(chan struct{}, 1)
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		select {
+		case flushed <- struct{}{}:
+		default:
+		}
+		return 0, syscall.EIO
+	}).MinTimes(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushType = cfgs2.FlushByTime
+	fsConfig.FlushDuration = 10 * time.Millisecond
+	fsConfig.Logger = log
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{2}, 0, io.SeekStart)
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("buffered data should be flushed by timer")
+	}
+
+	f.(*filesystem).stopFlusher()
+
+	// failure is reported only by filesystem which handles it, writer just returns it
+	assert.NotEmpty(t, log.get("error"))
+	for _, msg := range log.get("error") {
+		assert.Contains(t, msg, ErrFilesystemCouldNotFlush.Error())
+	}
+}
+
+func TestFilesystem_Logger_SlowOperation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+	log := newRecordLogger()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Sync().DoAndReturn(func() error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.Logger = log
+	fsConfig.SlowOperationThreshold = time.Millisecond
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	assert.Nil(t, f.Sync())
+	assert.Equal(t, []string{"package fs - slow operation"}, log.get("warn"))
+}
+
+func TestFilesystem_Readers_Concurrent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().ReadAt(gomock.Any(), int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		return len(p), nil
+	}).AnyTimes()
+	mockFile.EXPECT().Close().Return(nil).Times(4)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).MaxTimes(4)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+	fsConfig.ReaderLimit = 4
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	// open all readers before closing, so number of closed files is known
+	var acquired []reader.FileReader
+	for i := 0; i < 4; i++ {
+		r, err := f.AcquireReader()
+		assert.Nil(t, err)
+		acquired = append(acquired, r)
+	}
+	for _, r := range acquired {
+		assert.Nil(t, f.ReleaseReader(r))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					if _, err := f.ReadData(0, 1, io.SeekStart); err != nil {
+						assert.ErrorIs(t, err, ErrFilesystemReaderOccupying)
+					}
+				case 1:
+					_, err := f.GetReaderState()
+					assert.Nil(t, err)
+				case 2:
+					_, err := f.GetReaderId()
+					assert.Nil(t, err)
+				default:
+					if r, err := f.AcquireReader(); err == nil {
+						assert.Nil(t, f.ReleaseReader(r))
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Nil(t, f.CloseReader())
+}
+
+func TestFilesystem_CloseReader_Concurrent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().ReadAt(gomock.Any(), int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		return len(p), nil
+	}).AnyTimes()
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	var closed sync.WaitGroup
+	var closes, reads sync.Map
+
+	for i := 0; i < 8; i++ {
+		closed.Add(2)
+		go func(i int) {
+			defer closed.Done()
+			closes.Store(i, f.CloseReader())
+		}(i)
+		go func(i int) {
+			defer closed.Done()
+			_, err := f.ReadData(0, 1, io.SeekStart)
+			reads.Store(i, err)
+		}(i)
+	}
+	closed.Wait()
+
+	// readers are closed exactly once, other callers see they are occupied or closed
+	succeeded := 0
+	closes.Range(func(_, v any) bool {
+		if v == nil {
+			succeeded++
+		} else {
+			assert.True(t, errors.Is(v.(error), ErrFilesystemReaderOccupying) || errors.Is(v.(error), ErrFilesystemReaderNil))
+		}
+		return true
+	})
+	assert.Equal(t, 1, succeeded)
+
+	reads.Range(func(_, v any) bool {
+		if v != nil {
+			assert.True(t, errors.Is(v.(error), ErrFilesystemReaderOccupying) || errors.Is(v.(error), ErrFilesystemReaderNil))
+		}
+		return true
+	})
+}
+
+func TestFilesystem_Hooks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1, 2}, int64(0)).Return(2, nil).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(1)).Return(1, nil).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(2)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	var events []string
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Hooks = cfgs2.Hooks{
+		OnOpen: func(fPath string) {
+			events = append(events, "open "+fPath)
+		},
+		OnClose: func(fPath string, err error) {
+			events = append(events, "close "+fPath)
+			assert.Nil(t, err)
+		},
+		OnFlush: func(fPath string, bytes int, d time.Duration, err error) {
+			events = append(events, "flush "+strconv.Itoa(bytes))
+			assert.Nil(t, err)
+		},
+		OnWrite: func(fPath string, bytes int, d time.Duration, err error) {
+			events = append(events, "write "+strconv.Itoa(bytes))
+			assert.Nil(t, err)
+		},
+		OnRead: func(fPath string, bytes int, d time.Duration, err error) {
+			events = append(events, "read "+strconv.Itoa(bytes))
+			assert.Nil(t, err)
+		},
+	}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{1}, 0, io.SeekStart)
+	_ = f.Write([]byte{2}, 0, io.SeekCurrent)
+	_, _ = f.ReadData(1, 1, io.SeekStart)
+	_ = f.Close()
+
+	assert.Equal(t, []string{"open " + someFilePath, "write 1", "write 1", "flush 2", "read 1", "close " + someFilePath}, events)
+}
+
+func TestFilesystem_Hooks_Error(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{2}, int64(0)).Return(0, syscall.ENOSPC).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	var flushErr, writeErr, hookErr error
+	var hookOp string
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushSize = 0
+	fsConfig.Hooks = cfgs2.Hooks{
+		OnFlush: func(fPath string, bytes int, d time.Duration, err error) {
+			flushErr = err
+		},
+		OnWrite: func(fPath string, bytes int, d time.Duration, err error) {
+			writeErr = err
+		},
+		OnError: func(fPath string, op string, err error) {
+			hookOp, hookErr = op, err
+		},
+	}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.Write([]byte{2}, 0, io.SeekStart)
+
+	assert.ErrorIs(t, flushErr, syscall.ENOSPC)
+	assert.Equal(t, err, writeErr)
+	assert.Equal(t, "write", hookOp)
+	assert.Equal(t, err, hookErr)
+	assert.ErrorIs(t, hookErr, ErrFilesystemCouldNotWrite)
+}
+
+func TestFilesystem_WriteContext_ContextIsDone(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	// first write hangs like a write into a hung mount
+	unblock := make(chan struct{})
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(0)).DoAndReturn(func(b []byte, off int64) (int, error) {
+		<-unblock
+		return len(b), nil
+	}).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushSize = 0
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	written := make(chan error)
+	go func() {
+		written <- f.Write([]byte{1}, 0, io.SeekStart)
+	}()
+
+	assert.Eventually(t, func() bool { return len(f.(*filesystem).writerMu) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := f.WriteContext(ctx, []byte{2}, 1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFilesystemContextDone)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var fsErr *errs.Error
+	assert.ErrorAs(t, err, &fsErr)
+	assert.Equal(t, "write", fsErr.Op)
+
+	err = f.SyncContext(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = f.FlushContext(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(unblock)
+
+	assert.Nil(t, <-written)
+}
+
+func TestFilesystem_SyncContext(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(0)).Return(1, nil).Times(1)
+	mockFile.EXPECT().Sync().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.Nil(t, f.WriteContext(ctx, []byte{1}, 0, io.SeekStart))
+	assert.Nil(t, f.SyncContext(ctx))
+}
+
+func TestFilesystem_SyncContext_ContextIsCanceled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := f.SyncContext(ctx)
+
+	assert.ErrorIs(t, err, ErrFilesystemContextDone)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFilesystem_SyncContext_FsyncIsSlow(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	unblock := make(chan struct{})
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Sync().DoAndReturn(func() error {
+		<-unblock
+		return nil
+	}).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// caller gives up waiting for fsync which is in flight
+	err := f.SyncContext(ctx)
+	close(unblock)
+
+	assert.ErrorIs(t, err, ErrFilesystemContextDone)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrFilesystemWriterCouldNotSync)
+}
+
+func TestFilesystem_ReadDataContext_WaitsForReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, _ := f.AcquireReader()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = f.ReleaseReader(r)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rawData, err := f.ReadDataContext(ctx, 0, 1, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0}, rawData)
+}
+
+func TestFilesystem_ReadDataContext_ContextIsDone(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, _ = f.AcquireReader()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := f.ReadDataContext(ctx, 0, 1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFilesystemContextDone)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = f.ReadAllDataContext(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = f.AcquireReaderContext(ctx)
+
+	var fsErr *errs.Error
+	assert.ErrorAs(t, err, &fsErr)
+	assert.Equal(t, "acquire reader", fsErr.Op)
+}
+
+func TestFilesystem_AcquireReaderContext_ReadersAreClosed(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, _ = f.AcquireReader()
+
+	acquired := make(chan error)
+	go func() {
+		_, err := f.AcquireReaderContext(context.Background())
+		acquired <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, f.Close())
+
+	assert.ErrorIs(t, <-acquired, ErrFilesystemReaderNil)
+}
+
+func TestFilesystem_Append(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+
+	// end of file is recovered from its size only once
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(10)).Times(1)
+	mockFile.EXPECT().WriteAt([]byte{1, 2, 3}, int64(10)).Return(3, nil).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	offset1, err1 := f.Append([]byte{1, 2})
+	offset2, err2 := f.Append([]byte{3})
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, int64(10), offset1)
+	assert.Equal(t, int64(12), offset2)
+
+	// buffered appends are flushed by one write
+	assert.Nil(t, f.Flush())
+}
+
+func TestFilesystem_Append_CouldNotFlush_TakesDataBack(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
+
+	// failed append is written only by its retry
+	gomock.InOrder(
+		mockFile.EXPECT().WriteAt([]byte("rec1"), int64(0)).Return(0, syscall.ENOSPC).Times(1),
+		mockFile.EXPECT().WriteAt([]byte("rec1"), int64(0)).Return(4, nil).Times(1),
+	)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+	fsConfig.FlushPolicy = cfgs2.FlushPolicy{Writes: 1}
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, err := f.Append([]byte("rec1"))
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotWrite)
+	assert.Equal(t, uint64(0), f.Stats().Buffered)
+
+	offset, err := f.Append([]byte("rec1"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Nil(t, f.Flush())
+}
+
+func TestFilesystem_Append_AfterWrite(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	// buffered data of Write is counted in end of file
+	_ = f.Write([]byte{1, 2, 3}, 5, io.SeekStart)
+
+	offset, err := f.Append([]byte{4})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), offset)
+}
+
+func TestFilesystem_Append_Writer_Nil(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, err := f.Append([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
+}
+
+func TestFilesystem_Append_CouldNotStat(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFile.EXPECT().Stat().Return(nil, syscall.EIO).Times(1)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_, err := f.Append([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotWrite)
+	assert.ErrorIs(t, err, syscall.EIO)
+}
+
+func TestFilesystem_ReplaceAll(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockTmpFile := mockfile.NewMockFile(mockCtrl)
+	mockDir := mockfile.NewMockFile(mockCtrl)
+	newMockFile := mockfile.NewMockFile(mockCtrl)
+	newMockReaderFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+
+	// raw data is written & synced into a temporary file, then it's renamed over file and directory is synced
+	var tmpPath string
+	mockFileHelper.EXPECT().OpenFile(gomock.Any(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, cfgs.DefaultFileMode).DoAndReturn(func(name string, flag int, perm os.FileMode) (*mockfile.MockFile, error) {
+		tmpPath = name
+		return mockTmpFile, nil
+	}).Times(1)
+	mockTmpFile.EXPECT().Write([]byte{1, 2, 3}).Return(3, nil).Times(1)
+	mockTmpFile.EXPECT().Sync().Return(nil).Times(1)
+	mockTmpFile.EXPECT().Close().Return(nil).Times(1)
+	var renamed []string
+	mockFileHelper.EXPECT().Rename(gomock.Any(), someFilePath).DoAndReturn(func(oldPath, newPath string) error {
+		renamed = []string{oldPath, newPath}
+		return nil
+	}).Times(1)
+	mockFileHelper.EXPECT().OpenFile("/test", os.O_RDONLY, os.FileMode(0)).Return(mockDir, nil).Times(1)
+	mockDir.EXPECT().Sync().Return(nil).Times(1)
+	mockDir.EXPECT().Close().Return(nil).Times(1)
+
+	// writer is reopened on the new file, buffered data of the previous content is never written
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(newMockFile, nil).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+	newMockFile.EXPECT().WriteAt([]byte{4}, int64(3)).Return(1, nil).Times(1)
+
+	// acquired reader is reopened on the new file once it's released
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(newMockReaderFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	r, _ := f.AcquireReader()
+	_ = f.Write([]byte{9}, 0, io.SeekStart)
+
+	err := f.ReplaceAll([]byte{1, 2, 3})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{tmpPath, someFilePath}, renamed)
+	assert.Equal(t, "/test", filepath.Dir(tmpPath))
+	assert.Equal(t, uint64(0), f.Stats().Buffered)
+
+	assert.Nil(t, f.ReleaseReader(r))
+
+	newReader, err := f.AcquireReader()
+	assert.Nil(t, err)
+	assert.NotEqual(t, r, newReader)
+
+	offset, err := f.Append([]byte{4})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), offset)
+	assert.Nil(t, f.Flush())
+}
+
+func TestFilesystem_ReplaceAll_CouldNotRename(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockTmpFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(gomock.Any(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, cfgs.DefaultFileMode).Return(mockTmpFile, nil).Times(1)
+	mockTmpFile.EXPECT().Write([]byte{1}).Return(1, nil).Times(1)
+	mockTmpFile.EXPECT().Sync().Return(nil).Times(1)
+	mockTmpFile.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().Rename(gomock.Any(), someFilePath).Return(syscall.EXDEV).Times(1)
+
+	// temporary file is removed
+	var removed string
+	mockFileHelper.EXPECT().Remove(gomock.Any()).DoAndReturn(func(path string) error {
+		removed = path
+		return nil
+	}).Times(1)
+
+	// file is not replaced, so buffered data is still written into it
+	mockFile.EXPECT().WriteAt([]byte{9}, int64(0)).Return(1, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	_ = f.Write([]byte{9}, 0, io.SeekStart)
+
+	err := f.ReplaceAll([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotReplace)
+	assert.ErrorIs(t, err, syscall.EXDEV)
+	assert.NotEmpty(t, removed)
+	assert.Nil(t, f.Flush())
+}
+
+func TestFilesystem_ReplaceAll_CouldNotWriteTemporaryFile(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockTmpFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(gomock.Any(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, cfgs.DefaultFileMode).Return(mockTmpFile, nil).Times(1)
+	mockTmpFile.EXPECT().Write([]byte{1}).Return(0, syscall.ENOSPC).Times(1)
+	mockTmpFile.EXPECT().Close().Return(nil).Times(1)
+
+	// temporary file is removed without renaming it over file
+	mockFileHelper.EXPECT().Remove(gomock.Any()).Return(nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.ReplaceAll([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemCouldNotReplace)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+}
+
+func TestFilesystem_ReplaceAll_CouldNotReopenWriter(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockTmpFile := mockfile.NewMockFile(mockCtrl)
+	mockDir := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(gomock.Any(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, cfgs.DefaultFileMode).Return(mockTmpFile, nil).Times(1)
+	mockTmpFile.EXPECT().Write([]byte{1}).Return(1, nil).Times(1)
+	mockTmpFile.EXPECT().Sync().Return(nil).Times(1)
+	mockTmpFile.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().Rename(gomock.Any(), someFilePath).Return(nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile("/test", os.O_RDONLY, os.FileMode(0)).Return(mockDir, nil).Times(1)
+	mockDir.EXPECT().Sync().Return(nil).Times(1)
+	mockDir.EXPECT().Close().Return(nil).Times(1)
+
+	// writer of the replaced file is closed once it could not be reopened on the new file
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_WRONLY|os.O_CREATE, cfgs.DefaultFileMode).Return(nil, syscall.EMFILE).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.WOnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.ReplaceAll([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemReplacedButNotReopened)
+	assert.NotErrorIs(t, err, ErrFilesystemCouldNotReplace)
+	assert.ErrorIs(t, err, syscall.EMFILE)
+
+	// writes are refused instead of going into the unlinked file
+	assert.ErrorIs(t, f.Write([]byte{2}, 0, io.SeekStart), ErrFilesystemWriterBroken)
+	_, err = f.Append([]byte{2})
+	assert.ErrorIs(t, err, ErrFilesystemWriterBroken)
+	assert.ErrorIs(t, f.Sync(), ErrFilesystemWriterBroken)
+	assert.Nil(t, f.Close())
+}
+
+func TestFilesystem_ReplaceAll_CouldNotReopenReader(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockReaderFile := mockfile.NewMockFile(mockCtrl)
+	mockTmpFile := mockfile.NewMockFile(mockCtrl)
+	mockDir := mockfile.NewMockFile(mockCtrl)
+	newMockFile := mockfile.NewMockFile(mockCtrl)
+	newMockReaderFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(mockFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockReaderFile, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(gomock.Any(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, cfgs.DefaultFileMode).Return(mockTmpFile, nil).Times(1)
+	mockTmpFile.EXPECT().Write([]byte{1}).Return(1, nil).Times(1)
+	mockTmpFile.EXPECT().Sync().Return(nil).Times(1)
+	mockTmpFile.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().Rename(gomock.Any(), someFilePath).Return(nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile("/test", os.O_RDONLY, os.FileMode(0)).Return(mockDir, nil).Times(1)
+	mockDir.EXPECT().Sync().Return(nil).Times(1)
+	mockDir.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDWR|os.O_CREATE, cfgs.DefaultFileMode).Return(newMockFile, nil).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	// free reader could not be reopened on the new file, so the only reader of filesystem is forgotten
+	mockReaderFile.EXPECT().Close().Return(nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(nil, syscall.EMFILE).Times(1)
+
+	// a new reader is opened on demand by the next read
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(newMockReaderFile, nil).Times(1)
+	newMockReaderFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(1, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.ReplaceAll([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemReplacedButNotReopened)
+	assert.ErrorIs(t, err, syscall.EMFILE)
+
+	_, err = f.ReadData(0, 1, io.SeekStart)
+
+	assert.Nil(t, err)
+}
+
+func TestFilesystem_ReplaceAll_Writer_Nil(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	someFilePath := filepath.Join("/test", "/test.txt")
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+
+	mockFileHelper := mockfile.NewMockFileHelper(mockCtrl)
+	mockFileHelper.EXPECT().Stat(someFilePath).Return(nil, nil).Times(1)
+	mockFileHelper.EXPECT().OpenFile(someFilePath, os.O_RDONLY, os.FileMode(0)).Return(mockFile, nil).Times(1)
+
+	fsConfig := cfgs.FSConfiguration{}
+	fsConfig.New()
+	fsConfig.Perm = cfgs2.ROnly
+
+	f, _ := NewFilesystem(someFilePath, fsConfig, mockFileHelper.Stat, mockFileHelper.IsNotExist, mockFileHelper.MkdirAll, mockFileHelper.OpenFile, mockFileHelper.Rename, mockFileHelper.Remove)
+
+	err := f.ReplaceAll([]byte{1})
+
+	assert.ErrorIs(t, err, ErrFilesystemWriterNil)
 }
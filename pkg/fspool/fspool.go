@@ -5,3 +5,947 @@ written for reading or writing into files.
 Examples are available at https://github.com/amirvalhalla/fspool/tree/master/examples
 */
 package fspool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fsConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fs"
+	fspoolConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fspool"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"github.com/amirvalhalla/fspool/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrFSPoolLimitIsZero             = errors.New("package fspool - limit of fspool should be greater than zero")
+	ErrFSPoolFilepathIsEmpty         = errors.New("package fspool - file path is empty")
+	ErrFSPoolLimitReached            = errors.New("package fspool - fspool has reached the limit of filesystem instances")
+	ErrFSPoolFilesystemIsNotExists   = errors.New("package fspool - filesystem instance of file path doesn't exist in fspool")
+	ErrFSPoolCouldNotCloseFilesystem = errors.New("package fspool - could not close filesystem instance")
+	ErrFSPoolFilesystemIsNotAcquired = errors.New("package fspool - filesystem instance has not been acquired from fspool")
+	ErrFSPoolClosed                  = errors.New("package fspool - fspool is closed")
+)
+
+type FSPool interface {
+	// Acquire returns filesystem instance of file path, if fspool has reached its limit it waits until an instance is released or ctx is done
+	Acquire(ctx context.Context, fPath string) (fs.Filesystem, error)
+	// Get returns filesystem instance of file path without waiting, it returns ErrFSPoolLimitReached if fspool has reached its limit
+	// or instance of file path has outlived MaxLifetime and it is still acquired by others
+	Get(fPath string) (fs.Filesystem, error)
+	// Release gives back filesystem instance to fspool, the instance will be closed (or kept idle for eviction policy) when it's not acquired by anyone else
+	// released instance should not be used anymore, because it may be evicted and reopened by next Acquire of its file path
+	Release(f fs.Filesystem) error
+	// Remove closes filesystem instance of file path even if it's acquired and frees its place in fspool
+	Remove(fPath string) error
+	// Len returns number of live filesystem instances
+	Len() int
+	// Stats returns a snapshot of fspool
+	Stats() Stats
+	// Close stops handing out instances and waits until acquired instances are released or ctx is done, then it flushes, syncs and closes all instances
+	// instances which are still acquired when ctx is done are closed as well, returned error lists files which could not be closed
+	Close(ctx context.Context) error
+}
+
+/*
+* Stats is a snapshot of fspool
+* OpenInstances: live filesystem instances (acquired and idle ones)
+* IdleInstances: instances which are kept open by eviction policy without any holder
+* Waiters: callers of Acquire which are waiting for a place in fspool
+* WaitCount & WaitDuration: number of Acquire calls which have waited and total duration of their waiting
+* WaitLatency: distribution of waiting of Acquire calls by fs.LatencyBuckets
+* LimitReached: number of Get calls which have failed by ErrFSPoolLimitReached
+* Evictions: number of closed idle instances by reason (limit, idle timeout, max lifetime)
+* Budget: how limit of fspool has been sized
+* Files: stats of live instances by file path
+* Total: sum of stats of all instances of fspool including closed ones
+ */
+type Stats struct {
+	OpenInstances int
+	IdleInstances int
+	Waiters       int
+	WaitCount     uint64
+	WaitDuration  time.Duration
+	WaitLatency   fs.Histogram
+	LimitReached  uint64
+	Evictions     map[string]uint64
+	Budget        Budget
+	Files         map[string]fs.Stats
+	Total         fs.Stats
+}
+
+// entry holds a live filesystem instance with number of its holders
+type entry struct {
+	fPath    string
+	f        fs.Filesystem
+	refs     int
+	uses     uint64        // number of times instance has been acquired (used by EvictLFU)
+	idle     *list.Element // element of entry in idle list while it's not acquired by anyone
+	opened   time.Time     // used by MaxLifetime
+	released time.Time     // last time entry has become idle (used by IdleTimeout)
+	evicted  chan struct{} // non-nil once entry has been evicted or removed, it's closed when its instance has been closed outside of p.mu
+	reason   string        // reason of eviction, it's reported once instance has been closed (empty means entry has been removed)
+	closeErr error         // error of closing removed instance, it's returned to caller of Release or Remove
+}
+
+// waiter is a caller of Acquire that waits in queue for a free place in fspool
+type waiter struct {
+	fPath    string
+	ready    chan struct{}
+	reserved bool   // true means a place of fspool has been reserved for waiter
+	e        *entry // instance of file path which has been acquired on behalf of waiter
+}
+
+type fsPool struct {
+	config         fspoolConfig.FSPoolConfiguration
+	fsConfig       fsConfig.FSConfiguration
+	instances      map[string]*entry
+	entries        map[fs.Filesystem]*entry
+	waiters        *list.List
+	idle           *list.List // released instances which are kept open by eviction policy, least recently used one at front
+	reserved       uint32
+	evicting       []*entry // evicted and removed entries which should be closed outside of p.mu by the next unlock
+	hooks          []func() // calls of hooks which are made outside of p.mu by the next unlock, so hooks may call fspool
+	closing        int      // number of evicted and removed entries which are not closed yet, they keep their places until they're closed
+	budget         Budget
+	waitCount      uint64
+	waitDuration   time.Duration
+	waitLatency    fs.Histogram
+	limitReached   uint64
+	evictions      map[string]uint64
+	closedStats    fs.Stats // sum of stats of closed instances
+	log            logger.Logger
+	done           chan struct{} // stops janitor
+	closed         bool
+	drained        chan struct{} // closed when there isn't any acquired instance after fspool is closed
+	mu             sync.Mutex
+	openFileFunc   fs.OpenFile
+	statFunc       fs.Stat
+	isNotExistFunc fs.IsNotExist
+	mkdirAllFunc   fs.MkdirAll
+	renameFunc     fs.Rename
+	removeFunc     fs.Remove
+}
+
+// NewFSPool provides new instance of fspool based on your configuration
+func NewFSPool(config fspoolConfig.FSPoolConfiguration) (FSPool, error) {
+	budget, err := newBudget(config, fileLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Limit = budget.Limit
+
+	p := &fsPool{
+		config:         config,
+		fsConfig:       fsConfiguration(config),
+		instances:      make(map[string]*entry),
+		entries:        make(map[fs.Filesystem]*entry),
+		waiters:        list.New(),
+		idle:           list.New(),
+		budget:         budget,
+		evictions:      make(map[string]uint64),
+		log:            logger.OrNop(config.Logger),
+		done:           make(chan struct{}),
+		openFileFunc:   openOSFile,
+		statFunc:       os.Stat,
+		isNotExistFunc: os.IsNotExist,
+		mkdirAllFunc:   os.MkdirAll,
+		renameFunc:     os.Rename,
+		removeFunc:     os.Remove,
+	}
+
+	if interval := janitorInterval(config); interval > 0 {
+		go p.janitor(interval)
+	}
+
+	return p, nil
+}
+
+// Acquire returns filesystem instance of file path, if fspool has reached its limit it waits until an instance is released or ctx is done
+func (p *fsPool) Acquire(ctx context.Context, fPath string) (fs.Filesystem, error) {
+	if fPath == "" {
+		return nil, ErrFSPoolFilepathIsEmpty
+	}
+
+	fPath = filepath.Clean(fPath)
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	if p.closed {
+		return nil, ErrFSPoolClosed
+	}
+
+	if e, ok := p.lookup(fPath); ok {
+		p.acquire(e)
+		return e.f, nil
+	}
+
+	// new callers don't overtake waiters, so places of fspool are handed out in FIFO order
+	// previous instance of file path which is still being closed blocks it, so it's never opened twice at once
+	if !p.blocked(fPath) && p.waiters.Len() == 0 && p.makePlace() {
+		return p.open(fPath)
+	}
+
+	w := &waiter{fPath: fPath, ready: make(chan struct{})}
+	elem := p.waiters.PushBack(w)
+	start := time.Now()
+
+	// waiters blocked by instances of their file paths don't hold the others back, so a free place may be handed to this one right away
+	p.notifyWaiters()
+
+	for {
+		p.unlock()
+
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+
+			p.observeWait(start)
+
+			select {
+			case <-w.ready:
+				// waiter has been woken up at the same time, so its reserved place or instance should be handed to the next waiter
+				if w.reserved {
+					p.reserved--
+				}
+				if w.e != nil {
+					_ = p.release(w.e)
+				}
+			default:
+				p.waiters.Remove(elem)
+			}
+
+			p.notifyWaiters()
+
+			return nil, ctx.Err()
+		case <-w.ready:
+		}
+
+		p.mu.Lock()
+
+		// instance which has been acquired on behalf of waiter before closing is handed over, Close waits for its release
+		if w.e != nil {
+			p.observeWait(start)
+			return w.e.f, nil
+		}
+
+		if w.reserved {
+			p.reserved--
+		}
+
+		if p.closed {
+			p.observeWait(start)
+			return nil, ErrFSPoolClosed
+		}
+
+		// instance of file path may have been opened by Get meanwhile
+		if e, ok := p.lookup(fPath); ok {
+			p.observeWait(start)
+			p.acquire(e)
+			p.notifyWaiters()
+			return e.f, nil
+		}
+
+		if !p.blocked(fPath) {
+			break
+		}
+
+		// instance of file path has been evicted or expired meanwhile, so waiter keeps its turn until it's closed
+		w = &waiter{fPath: fPath, ready: make(chan struct{})}
+		elem = p.waiters.PushFront(w)
+		p.notifyWaiters()
+	}
+
+	p.observeWait(start)
+
+	f, err := p.open(fPath)
+	if err != nil {
+		p.notifyWaiters()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Get returns filesystem instance of file path without waiting, it returns ErrFSPoolLimitReached if fspool has reached its limit
+// or instance of file path has outlived MaxLifetime and it is still acquired by others
+func (p *fsPool) Get(fPath string) (fs.Filesystem, error) {
+	if fPath == "" {
+		return nil, ErrFSPoolFilepathIsEmpty
+	}
+
+	fPath = filepath.Clean(fPath)
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	for {
+		if p.closed {
+			return nil, ErrFSPoolClosed
+		}
+
+		if e, ok := p.lookup(fPath); ok {
+			p.acquire(e)
+			return e.f, nil
+		}
+
+		// expired instance of file path is still acquired by others, so Get can't wait for it to be closed
+		if e, ok := p.instances[fPath]; ok {
+			if e.evicted == nil {
+				break
+			}
+
+			p.awaitEviction(e)
+			continue
+		}
+
+		if p.queued() {
+			break
+		}
+
+		if p.hasFreePlace() {
+			return p.open(fPath)
+		}
+
+		// closing an evicted instance is waited for, because it doesn't depend on holders of instances
+		e := p.evictionCandidate()
+		if e == nil {
+			break
+		}
+
+		p.evict(e, "limit")
+		p.awaitEviction(e)
+	}
+
+	p.limitReached++
+
+	return nil, ErrFSPoolLimitReached
+}
+
+// Release gives back filesystem instance to fspool, the instance will be closed when it's not acquired by anyone else
+func (p *fsPool) Release(f fs.Filesystem) error {
+	p.mu.Lock()
+	defer p.unlock()
+
+	// removed instance is not acquired by its holders anymore
+	e, ok := p.entries[f]
+	if !ok || e.refs == 0 || e.evicted != nil {
+		return ErrFSPoolFilesystemIsNotAcquired
+	}
+
+	return p.release(e)
+}
+
+// Remove closes filesystem instance of file path even if it's acquired and frees its place in fspool
+func (p *fsPool) Remove(fPath string) error {
+	fPath = filepath.Clean(fPath)
+
+	p.mu.Lock()
+	defer p.unlock()
+
+	e, ok := p.instances[fPath]
+	if !ok {
+		return ErrFSPoolFilesystemIsNotExists
+	}
+
+	// evicted or removed instance is being closed already
+	if e.evicted != nil {
+		p.awaitEviction(e)
+		return nil
+	}
+
+	return p.remove(e)
+}
+
+// Len returns number of live filesystem instances
+func (p *fsPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.instances)
+}
+
+// Stats returns a snapshot of fspool
+func (p *fsPool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{
+		OpenInstances: len(p.instances),
+		IdleInstances: p.idle.Len(),
+		Waiters:       p.waiters.Len(),
+		WaitCount:     p.waitCount,
+		WaitDuration:  p.waitDuration,
+		WaitLatency:   p.waitLatency,
+		LimitReached:  p.limitReached,
+		Evictions:     make(map[string]uint64, len(p.evictions)),
+		Budget:        p.budget,
+		Files:         make(map[string]fs.Stats, len(p.instances)),
+		Total:         p.closedStats.Add(fs.Stats{}), // copy of Errors map
+	}
+
+	for reason, n := range p.evictions {
+		stats.Evictions[reason] = n
+	}
+
+	for fPath, e := range p.instances {
+		fStats := e.f.Stats()
+		stats.Files[fPath] = fStats
+		stats.Total = stats.Total.Add(fStats)
+	}
+
+	return stats
+}
+
+// observeWait counts waiting of a caller of Acquire (caller must hold p.mu)
+func (p *fsPool) observeWait(start time.Time) {
+	d := time.Since(start)
+
+	p.waitCount++
+	p.waitDuration += d
+	p.waitLatency.Observe(d)
+}
+
+// Close stops handing out instances and waits until acquired instances are released or ctx is done, then it flushes, syncs and closes all instances
+func (p *fsPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return ErrFSPoolClosed
+	}
+
+	p.closed = true
+	p.drained = make(chan struct{})
+	close(p.done)
+
+	// waiters are woken up without any place, so they return ErrFSPoolClosed
+	for elem := p.waiters.Front(); elem != nil; elem = elem.Next() {
+		close(elem.Value.(*waiter).ready)
+	}
+	p.waiters.Init()
+
+	p.checkDrained()
+	p.mu.Unlock()
+
+	var ctxErr error
+
+	select {
+	case <-p.drained:
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
+	}
+
+	p.mu.Lock()
+
+	p.closeEvicted()
+
+	for _, e := range p.evictedEntries() {
+		p.awaitEviction(e)
+	}
+
+	// instances are taken out of fspool under p.mu, then they're synced and closed without holding it
+	closing := make([]*entry, 0, len(p.instances))
+	for _, e := range p.instances {
+		p.forget(e)
+		closing = append(closing, e)
+	}
+
+	p.unlock()
+
+	closeErrs := []error{ctxErr}
+
+	for _, e := range closing {
+		if err := p.closeInstance(e); err != nil {
+			closeErrs = append(closeErrs, err)
+			continue
+		}
+
+		p.log.Debug("package fspool - closed filesystem instance", "path", e.fPath)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range closing {
+		p.retire(e)
+	}
+
+	return errs.Join(closeErrs...)
+}
+
+// checkDrained closes p.drained if fspool is closed and there isn't any acquired instance (caller must hold p.mu)
+func (p *fsPool) checkDrained() {
+	if !p.closed {
+		return
+	}
+
+	select {
+	case <-p.drained:
+		return
+	default:
+	}
+
+	// holders of removed instance don't hold it anymore
+	for _, e := range p.instances {
+		if e.refs > 0 && e.evicted == nil {
+			return
+		}
+	}
+
+	close(p.drained)
+}
+
+// evictedEntries returns evicted entries whose instances are being closed (caller must hold p.mu)
+func (p *fsPool) evictedEntries() []*entry {
+	var evicted []*entry
+
+	for _, e := range p.instances {
+		if e.evicted != nil {
+			evicted = append(evicted, e)
+		}
+	}
+
+	return evicted
+}
+
+// hasFreePlace reports whether fspool can open another filesystem instance (caller must hold p.mu)
+func (p *fsPool) hasFreePlace() bool {
+	return uint32(len(p.instances))+p.reserved < p.config.Limit
+}
+
+// open creates filesystem instance of file path and registers it as acquired once (caller must hold p.mu)
+func (p *fsPool) open(fPath string) (fs.Filesystem, error) {
+	f, err := p.newFilesystem(fPath)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{fPath: fPath, f: f, refs: 1, uses: 1, opened: time.Now()}
+	p.instances[fPath] = e
+	p.entries[f] = e
+
+	p.log.Debug("package fspool - opened filesystem instance", "path", fPath)
+
+	// OnOpen hook of instances is called by fspool outside of p.mu instead of filesystem instance
+	if onOpen := p.config.Hooks.OnOpen; onOpen != nil {
+		p.hooks = append(p.hooks, func() { onOpen(fPath) })
+	}
+
+	p.notifyWaiters()
+
+	return f, nil
+}
+
+// acquire registers one more holder of entry and takes it out of idle list (caller must hold p.mu)
+func (p *fsPool) acquire(e *entry) {
+	e.refs++
+	e.uses++
+
+	if e.idle != nil {
+		p.idle.Remove(e.idle)
+		e.idle = nil
+	}
+}
+
+// lookup returns live entry of file path, an expired idle entry is evicted so a fresh instance will be opened instead (caller must hold p.mu)
+// entry which has outlived MaxLifetime is not handed out anymore even if it's acquired, it's evicted once its holders release it
+func (p *fsPool) lookup(fPath string) (*entry, bool) {
+	e, ok := p.instances[fPath]
+	if !ok || e.evicted != nil {
+		return nil, false
+	}
+
+	if reason := p.expired(e, time.Now()); reason != "" {
+		if e.refs == 0 {
+			p.evict(e, reason)
+		}
+		return nil, false
+	}
+
+	return e, true
+}
+
+// blocked reports whether file path has an instance which can't be handed out, so a new one is opened only after it's closed (caller must hold p.mu)
+func (p *fsPool) blocked(fPath string) bool {
+	_, ok := p.instances[fPath]
+	return ok
+}
+
+// release unregisters a holder of entry, entry is closed or kept idle based on eviction policy when it has no holder (caller must hold p.mu)
+func (p *fsPool) release(e *entry) error {
+	e.refs--
+	if e.refs > 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if p.config.Eviction == cfgs.EvictNone {
+		return p.remove(e)
+	}
+
+	// instance which has outlived MaxLifetime while it was acquired is evicted instead of being kept idle
+	if reason := p.expired(e, now); reason != "" {
+		p.evict(e, reason)
+		return nil
+	}
+
+	e.released = now
+	e.idle = p.idle.PushBack(e)
+	p.notifyWaiters()
+	p.checkDrained()
+
+	return nil
+}
+
+// makePlace reports whether fspool can open another filesystem instance, it evicts an idle instance if fspool has reached its limit (caller must hold p.mu)
+// place of evicted instance is freed once it's closed outside of p.mu, waiters are notified then
+func (p *fsPool) makePlace() bool {
+	if p.hasFreePlace() {
+		return true
+	}
+
+	// a place is going to be freed by an evicted instance, so another one is not evicted meanwhile
+	if p.closing > 0 {
+		return false
+	}
+
+	if e := p.evictionCandidate(); e != nil {
+		p.evict(e, "limit")
+	}
+
+	return false
+}
+
+// evictionCandidate returns idle entry which should be evicted first based on eviction policy, nil means there isn't any idle entry (caller must hold p.mu)
+func (p *fsPool) evictionCandidate() *entry {
+	elem := p.idle.Front()
+	if elem == nil {
+		return nil
+	}
+
+	candidate := elem.Value.(*entry)
+
+	// idle list is ordered by recency, so the least recently used one wins between instances with the same number of uses
+	if p.config.Eviction == cfgs.EvictLFU {
+		for elem = elem.Next(); elem != nil; elem = elem.Next() {
+			if e := elem.Value.(*entry); e.uses < candidate.uses {
+				candidate = e
+			}
+		}
+	}
+
+	return candidate
+}
+
+// evict takes idle entry out of idle list and schedules its instance to be flushed, synced and closed by the next unlock (caller must hold p.mu)
+// entry keeps its place and file path until it's closed, so its file is never opened by another instance meanwhile
+func (p *fsPool) evict(e *entry, reason string) {
+	if e.idle != nil {
+		p.idle.Remove(e.idle)
+		e.idle = nil
+	}
+
+	p.detach(e, reason)
+
+	p.log.Debug("package fspool - evicted idle filesystem instance", "path", e.fPath, "uses", e.uses, "reason", reason)
+}
+
+// detach schedules instance of entry to be closed by the next unlock, empty reason means entry is removed instead of being evicted (caller must hold p.mu)
+func (p *fsPool) detach(e *entry, reason string) {
+	e.evicted = make(chan struct{})
+	e.reason = reason
+	p.evicting = append(p.evicting, e)
+	p.closing++
+}
+
+// closeEvicted closes instances of evicted and removed entries, failures of evicted ones are reported to logger because callers only need their places
+// p.mu is released while instances are flushed, synced and closed, then their places are handed to waiters (caller must hold p.mu)
+func (p *fsPool) closeEvicted() {
+	for len(p.evicting) > 0 {
+		evicting := p.evicting
+		p.evicting = nil
+
+		hooks := p.hooks
+		p.hooks = nil
+
+		p.mu.Unlock()
+
+		callHooks(hooks)
+
+		closeErrs := make([]error, len(evicting))
+		for i, e := range evicting {
+			if e.reason == "" {
+				closeErrs[i] = p.closeRemoved(e)
+				continue
+			}
+
+			closeErrs[i] = p.closeInstance(e)
+		}
+
+		p.mu.Lock()
+
+		for i, e := range evicting {
+			p.forget(e)
+			p.retire(e)
+			p.closing--
+
+			if e.reason == "" {
+				e.closeErr = closeErrs[i]
+			} else {
+				if closeErrs[i] != nil {
+					p.log.Error(closeErrs[i].Error(), "path", e.fPath)
+				}
+
+				p.evicted(e, e.reason)
+			}
+
+			close(e.evicted)
+		}
+
+		p.notifyWaiters()
+		p.checkDrained()
+	}
+}
+
+// awaitEviction waits until instance of evicted entry is closed, p.mu is released meanwhile (caller must hold p.mu)
+func (p *fsPool) awaitEviction(e *entry) {
+	p.closeEvicted()
+
+	select {
+	case <-e.evicted:
+		return
+	default:
+	}
+
+	// instance is being closed by another caller
+	p.mu.Unlock()
+	<-e.evicted
+	p.mu.Lock()
+}
+
+// unlock closes instances which have been evicted while holding p.mu, then it releases p.mu and calls queued hooks
+func (p *fsPool) unlock() {
+	p.closeEvicted()
+
+	hooks := p.hooks
+	p.hooks = nil
+
+	p.mu.Unlock()
+
+	callHooks(hooks)
+}
+
+// callHooks calls queued hooks in order they have been queued
+func callHooks(hooks []func()) {
+	for _, call := range hooks {
+		call()
+	}
+}
+
+// evicted counts eviction of entry by reason and queues its report to OnEvict hook (caller must hold p.mu)
+func (p *fsPool) evicted(e *entry, reason string) {
+	p.evictions[reason]++
+
+	if onEvict := p.config.Hooks.OnEvict; onEvict != nil {
+		fPath := e.fPath
+		p.hooks = append(p.hooks, func() { onEvict(fPath, reason) })
+	}
+}
+
+// closeInstance syncs and closes filesystem instance of entry, instance is closed even if syncing fails
+// it doesn't touch fspool, so evicted instances are closed without holding p.mu
+func (p *fsPool) closeInstance(e *entry) error {
+	syncErr := e.f.Sync()
+	if errors.Is(syncErr, fs.ErrFilesystemWriterNil) {
+		syncErr = nil
+	}
+
+	err := e.f.Close()
+
+	if err != nil {
+		return errs.New("close", e.fPath, errs.NoOffset, ErrFSPoolCouldNotCloseFilesystem, err)
+	}
+
+	if syncErr != nil {
+		return errs.New("sync", e.fPath, errs.NoOffset, ErrFSPoolCouldNotCloseFilesystem, syncErr)
+	}
+
+	return nil
+}
+
+// closeRemoved closes filesystem instance of removed entry, it's flushed by closing but it's not synced
+// it doesn't touch fspool, so removed instances are closed without holding p.mu
+func (p *fsPool) closeRemoved(e *entry) error {
+	if err := e.f.Close(); err != nil {
+		return errs.New("close", e.fPath, errs.NoOffset, ErrFSPoolCouldNotCloseFilesystem, err)
+	}
+
+	return nil
+}
+
+// retire adds stats of closed instance of entry to stats of fspool, its memory rent is not held anymore (caller must hold p.mu)
+func (p *fsPool) retire(e *entry) {
+	stats := e.f.Stats()
+	stats.Buffered, stats.MemoryRent = 0, 0
+	p.closedStats = p.closedStats.Add(stats)
+}
+
+// expired returns reason of expiration of entry based on MaxLifetime & IdleTimeout, empty string means entry is not expired (caller must hold p.mu)
+func (p *fsPool) expired(e *entry, now time.Time) string {
+	if p.config.MaxLifetime > 0 && now.Sub(e.opened) >= p.config.MaxLifetime {
+		return "max lifetime"
+	}
+
+	if p.config.IdleTimeout > 0 && e.idle != nil && now.Sub(e.released) >= p.config.IdleTimeout {
+		return "idle timeout"
+	}
+
+	return ""
+}
+
+// janitor closes expired idle instances periodically until fspool is stopped
+func (p *fsPool) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.closeExpired()
+		}
+	}
+}
+
+// closeExpired evicts idle instances which have outlived IdleTimeout or MaxLifetime, they're closed outside of p.mu
+func (p *fsPool) closeExpired() {
+	p.mu.Lock()
+	defer p.unlock()
+
+	now := time.Now()
+
+	for elem := p.idle.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry)
+
+		if reason := p.expired(e, now); reason != "" {
+			p.evict(e, reason)
+		}
+
+		elem = next
+	}
+}
+
+// janitorInterval returns how often janitor checks idle instances, 0 means janitor is not needed
+func janitorInterval(config fspoolConfig.FSPoolConfiguration) time.Duration {
+	interval := config.IdleTimeout
+	if config.MaxLifetime > 0 && (interval == 0 || config.MaxLifetime < interval) {
+		interval = config.MaxLifetime
+	}
+
+	return interval
+}
+
+// forget removes entry from fspool without closing its filesystem instance (caller must hold p.mu)
+func (p *fsPool) forget(e *entry) {
+	delete(p.instances, e.fPath)
+	delete(p.entries, e.f)
+
+	if e.idle != nil {
+		p.idle.Remove(e.idle)
+		e.idle = nil
+	}
+}
+
+// remove closes filesystem instance of entry outside of p.mu and waits for it, its place is handed to waiters once it's closed (caller must hold p.mu)
+// entry keeps its file path until it's closed, so its file is never opened by another instance meanwhile
+func (p *fsPool) remove(e *entry) error {
+	if e.idle != nil {
+		p.idle.Remove(e.idle)
+		e.idle = nil
+	}
+
+	p.detach(e, "")
+	p.awaitEviction(e)
+
+	if e.closeErr != nil {
+		return e.closeErr
+	}
+
+	p.log.Debug("package fspool - closed filesystem instance", "path", e.fPath)
+
+	return nil
+}
+
+// queued reports whether there is a waiter which waits for a place of fspool, waiters blocked by instances of their file paths are skipped (caller must hold p.mu)
+func (p *fsPool) queued() bool {
+	for elem := p.waiters.Front(); elem != nil; elem = elem.Next() {
+		if !p.blocked(elem.Value.(*waiter).fPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notifyWaiters wakes waiters up whose file path has been opened already and hands free places of fspool to the rest in FIFO order (caller must hold p.mu)
+func (p *fsPool) notifyWaiters() {
+	for elem := p.waiters.Front(); elem != nil; {
+		next := elem.Next()
+		w := elem.Value.(*waiter)
+
+		if e, ok := p.lookup(w.fPath); ok {
+			// instance is acquired on behalf of waiter, so it can't be evicted before waiter gets it
+			p.acquire(e)
+			w.e = e
+			p.waiters.Remove(elem)
+			close(w.ready)
+		} else if p.blocked(w.fPath) {
+			// waiter keeps its turn until instance of its file path is closed, others may get places meanwhile
+		} else if p.makePlace() {
+			p.waiters.Remove(elem)
+			w.reserved = true
+			p.reserved++
+			close(w.ready)
+		} else {
+			break
+		}
+
+		elem = next
+	}
+}
+
+// newFilesystem creates new filesystem instance of file path based on configuration of fspool
+func (p *fsPool) newFilesystem(fPath string) (fs.Filesystem, error) {
+	return fs.NewFilesystem(fPath, p.fsConfig, p.statFunc, p.isNotExistFunc, p.mkdirAllFunc, p.openFileFunc, p.renameFunc, p.removeFunc)
+}
+
+// fsConfiguration maps configuration of fspool to configuration of its filesystem instances, OnOpen hook is left to fspool
+// because instances are opened while holding p.mu
+func fsConfiguration(config fspoolConfig.FSPoolConfiguration) fsConfig.FSConfiguration {
+	fsConf := config.MapToFsConfiguration()
+	fsConf.Hooks.OnOpen = nil
+
+	return fsConf
+}
+
+// openOSFile opens file by os package
+func openOSFile(name string, flag int, perm os.FileMode) (file.File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
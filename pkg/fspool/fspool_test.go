@@ -0,0 +1,1182 @@
+package fspool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fspoolConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fspool"
+	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestConfig(limit uint32) fspoolConfig.FSPoolConfiguration {
+	return fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       limit,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	}
+}
+
+func TestNewFSPool(t *testing.T) {
+	pool, err := NewFSPool(newTestConfig(1))
+
+	assert.Nil(t, err)
+	assert.NotNil(t, pool)
+}
+
+func TestNewFSPool_AutoLimit(t *testing.T) {
+	pool, err := NewFSPool(newTestConfig(0))
+
+	assert.Nil(t, err)
+
+	budget := pool.Stats().Budget
+
+	assert.True(t, budget.Auto)
+	assert.Equal(t, uint64(3), budget.DescriptorsPerInstance)
+	assert.NotZero(t, budget.Limit)
+}
+
+func TestFSPool_Get(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test", "test.txt")
+
+	f, err := pool.Get(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, f.Sync())
+
+	rawData, err := f.ReadData(0, 6, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_Get_SameInstanceForSamePath(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f1, _ := pool.Get(someFilePath)
+	f2, err := pool.Get(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Same(t, f1, f2)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_Get_EmptyFilePath(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+
+	_, err := pool.Get("")
+
+	assert.ErrorIs(t, err, ErrFSPoolFilepathIsEmpty)
+}
+
+func TestFSPool_Get_LimitReached(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	_, _ = pool.Get(filepath.Join(someDirPath, "test1.txt"))
+	_, err := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolLimitReached)
+}
+
+func TestFSPool_Get_FileIsNotExists_With_ROnly_Perm(t *testing.T) {
+	config := newTestConfig(1)
+	config.Perm = cfgs.ROnly
+	pool, _ := NewFSPool(config)
+
+	_, err := pool.Get(filepath.Join(t.TempDir(), "test.txt"))
+
+	assert.ErrorIs(t, err, fs.ErrFileIsNotExists)
+	assert.Equal(t, 0, pool.Len())
+}
+
+func TestFSPool_Remove(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	_, _ = pool.Get(filepath.Join(someDirPath, "test1.txt"))
+	err := pool.Remove(filepath.Join(someDirPath, "test1.txt"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pool.Len())
+
+	_, err = pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.Nil(t, err)
+}
+
+func TestFSPool_Remove_FilesystemIsNotExists(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+
+	err := pool.Remove(filepath.Join(t.TempDir(), "test.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolFilesystemIsNotExists)
+}
+
+func TestFSPool_Acquire(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, err := pool.Acquire(context.Background(), someFilePath)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, f)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_Acquire_EmptyFilePath(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+
+	_, err := pool.Acquire(context.Background(), "")
+
+	assert.ErrorIs(t, err, ErrFSPoolFilepathIsEmpty)
+}
+
+func TestFSPool_Acquire_WaitsUntilRelease(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f1, _ := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test1.txt"))
+
+	acquired := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test2.txt"))
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire should wait until an instance is released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Nil(t, pool.Release(f1))
+	assert.Nil(t, <-acquired)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_Acquire_ContextIsDone(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f1, _ := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test1.txt"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := pool.Acquire(ctx, filepath.Join(someDirPath, "test2.txt"))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.Nil(t, pool.Release(f1))
+
+	_, err = pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.Nil(t, err)
+}
+
+func TestFSPool_Acquire_FIFO(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f, _ := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test0.txt"))
+
+	order := make(chan int, 3)
+	for i := 1; i <= 3; i++ {
+		fPath := filepath.Join(someDirPath, "test"+strconv.Itoa(i)+".txt")
+		go func(i int) {
+			f, err := pool.Acquire(context.Background(), fPath)
+			assert.Nil(t, err)
+			order <- i
+			assert.Nil(t, pool.Release(f))
+		}(i)
+
+		// makes sure waiters are queued in order
+		assert.Eventually(t, func() bool { return waitersLen(pool) == i }, time.Second, time.Millisecond)
+	}
+
+	assert.Nil(t, pool.Release(f))
+
+	assert.Equal(t, 1, <-order)
+	assert.Equal(t, 2, <-order)
+	assert.Equal(t, 3, <-order)
+}
+
+func TestFSPool_Acquire_SharesInstanceWithWaitersOfSamePath(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f1, _ := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test1.txt"))
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test2.txt"))
+			results <- err
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return waitersLen(pool) == 2 }, time.Second, time.Millisecond)
+	assert.Nil(t, pool.Release(f1))
+
+	assert.Nil(t, <-results)
+	assert.Nil(t, <-results)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_Release_KeepsInstanceWhileAcquired(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Acquire(context.Background(), someFilePath)
+	_, _ = pool.Acquire(context.Background(), someFilePath)
+
+	assert.Nil(t, pool.Release(f))
+	assert.Equal(t, 1, pool.Len())
+
+	assert.Nil(t, pool.Release(f))
+	assert.Equal(t, 0, pool.Len())
+}
+
+func TestFSPool_Release_FilesystemIsNotAcquired(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Acquire(context.Background(), someFilePath)
+	_ = pool.Release(f)
+
+	err := pool.Release(f)
+
+	assert.ErrorIs(t, err, ErrFSPoolFilesystemIsNotAcquired)
+}
+
+func waitersLen(pool FSPool) int {
+	p := pool.(*fsPool)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.waiters.Len()
+}
+
+func TestFSPool_Get_ReaderLimit(t *testing.T) {
+	config := newTestConfig(1)
+	config.ReaderLimit = 2
+	pool, _ := NewFSPool(config)
+
+	f, _ := pool.Get(filepath.Join(t.TempDir(), "test.txt"))
+
+	r1, err1 := f.AcquireReader()
+	r2, err2 := f.AcquireReader()
+	_, err3 := f.AcquireReader()
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.NotEqual(t, r1.GetId(), r2.GetId())
+	assert.ErrorIs(t, err3, fs.ErrFilesystemReaderOccupying)
+}
+
+func TestFSPool_Get_FileMode(t *testing.T) {
+	config := newTestConfig(1)
+	config.FileMode = 0600
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test", "test.txt")
+
+	_, err := pool.Get(someFilePath)
+
+	assert.Nil(t, err)
+
+	fInfo, err := os.Stat(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), fInfo.Mode().Perm())
+}
+
+func TestFSPool_Release_KeepsIdleInstance_With_EvictLRU(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f1, _ := pool.Get(someFilePath)
+	assert.Nil(t, pool.Release(f1))
+	assert.Equal(t, 1, pool.Len())
+
+	f2, err := pool.Get(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Same(t, f1, f2)
+}
+
+func TestFSPool_Get_EvictsLeastRecentlyUsed(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+	fPath2 := filepath.Join(someDirPath, "test2.txt")
+
+	f1, _ := pool.Get(fPath1)
+	f2, _ := pool.Get(fPath2)
+
+	// buffered data of evicted instance must be flushed into file
+	assert.Nil(t, f1.Write([]byte("fspool"), 0, io.SeekStart))
+
+	assert.Nil(t, pool.Release(f1))
+	assert.Nil(t, pool.Release(f2))
+
+	f2, _ = pool.Get(fPath2)
+	assert.Nil(t, pool.Release(f2))
+
+	// test1.txt is the least recently used one
+	_, err := pool.Get(filepath.Join(someDirPath, "test3.txt"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pool.Len())
+
+	rawData, err := os.ReadFile(fPath1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+
+	f2Again, _ := pool.Get(fPath2)
+	assert.Same(t, f2, f2Again)
+}
+
+func TestFSPool_Get_EvictsLeastFrequentlyUsed(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLFU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+	fPath2 := filepath.Join(someDirPath, "test2.txt")
+
+	f1, _ := pool.Get(fPath1)
+	f2, _ := pool.Get(fPath2)
+	assert.Nil(t, pool.Release(f2))
+
+	for i := 0; i < 3; i++ {
+		f, _ := pool.Get(fPath1)
+		assert.Nil(t, pool.Release(f))
+	}
+	assert.Nil(t, pool.Release(f1))
+
+	// test2.txt is used once, so it's evicted although test1.txt has been released later
+	_, err := pool.Get(filepath.Join(someDirPath, "test3.txt"))
+
+	assert.Nil(t, err)
+
+	f1Again, _ := pool.Get(fPath1)
+	assert.Same(t, f1, f1Again)
+}
+
+func TestFSPool_Get_ReopensEvictedInstance(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+
+	f1, _ := pool.Get(fPath1)
+	assert.Nil(t, f1.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f1))
+
+	f2, _ := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+	assert.Nil(t, pool.Release(f2))
+
+	f1, err := pool.Get(fPath1)
+
+	assert.Nil(t, err)
+
+	rawData, err := f1.ReadData(0, 6, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_Get_LimitReached_With_EvictLRU(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+
+	// acquired instances are never evicted
+	_, _ = pool.Get(filepath.Join(someDirPath, "test1.txt"))
+	_, err := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolLimitReached)
+}
+
+func TestFSPool_Acquire_WaiterEvictsReleasedInstance(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+
+	f1, _ := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test1.txt"))
+
+	acquired := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test2.txt"))
+		acquired <- err
+	}()
+
+	assert.Eventually(t, func() bool { return waitersLen(pool) == 1 }, time.Second, time.Millisecond)
+	assert.Nil(t, pool.Release(f1))
+
+	assert.Nil(t, <-acquired)
+	assert.Equal(t, 1, pool.Len())
+	assert.ErrorIs(t, pool.Release(f1), ErrFSPoolFilesystemIsNotAcquired)
+}
+
+type blockingSyncFile struct {
+	*os.File
+	syncing chan struct{}
+	unblock chan struct{}
+}
+
+func (f blockingSyncFile) Sync() error {
+	close(f.syncing)
+	<-f.unblock
+	return f.File.Sync()
+}
+
+func TestFSPool_Get_ClosesEvictedInstanceWithoutBlockingFSPool(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+
+	syncing := make(chan struct{})
+	unblock := make(chan struct{})
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || name != fPath1 || flag == os.O_RDONLY {
+			return f, err
+		}
+		return blockingSyncFile{File: f, syncing: syncing, unblock: unblock}, nil
+	}
+
+	f1, _ := pool.Get(fPath1)
+	assert.Nil(t, pool.Release(f1))
+	f2, _ := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	acquired := make(chan error)
+	go func() {
+		_, err := pool.Get(filepath.Join(someDirPath, "test3.txt"))
+		acquired <- err
+	}()
+
+	// test1.txt is being synced by eviction, fspool keeps serving others meanwhile
+	<-syncing
+	assert.Nil(t, pool.Release(f2))
+	assert.Equal(t, 1, pool.Stats().IdleInstances)
+
+	close(unblock)
+
+	assert.Nil(t, <-acquired)
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, map[string]uint64{"limit": 1}, pool.Stats().Evictions)
+}
+
+func TestFSPool_Get_WaitsForEvictedInstanceOfFilePath(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+
+	// only the first writer of test1.txt blocks on syncing
+	syncing := make(chan struct{})
+	unblock := make(chan struct{})
+	wrapped := false
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || name != fPath1 || flag == os.O_RDONLY || wrapped {
+			return f, err
+		}
+		wrapped = true
+		return blockingSyncFile{File: f, syncing: syncing, unblock: unblock}, nil
+	}
+
+	f1, _ := pool.Get(fPath1)
+	assert.Nil(t, f1.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f1))
+	f2, _ := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	evicted := make(chan error)
+	go func() {
+		_, err := pool.Get(filepath.Join(someDirPath, "test3.txt"))
+		evicted <- err
+	}()
+
+	<-syncing
+
+	// test1.txt is not opened again before its evicted instance is closed
+	reopened := make(chan fs.Filesystem)
+	go func() {
+		_ = pool.Release(f2)
+		f, _ := pool.Acquire(context.Background(), fPath1)
+		reopened <- f
+	}()
+
+	select {
+	case <-reopened:
+		t.Fatal("file path should not be opened while its evicted instance is being closed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(unblock)
+
+	assert.Nil(t, <-evicted)
+	f1Again := <-reopened
+
+	rawData, err := f1Again.ReadData(0, 6, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+type blockingCloseFile struct {
+	*os.File
+	closing chan struct{}
+	unblock chan struct{}
+}
+
+func (f blockingCloseFile) Close() error {
+	close(f.closing)
+	<-f.unblock
+	return f.File.Close()
+}
+
+func TestFSPool_Remove_ClosesInstanceWithoutBlockingFSPool(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(2))
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+
+	closing := make(chan struct{})
+	unblock := make(chan struct{})
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || name != fPath1 || flag == os.O_RDONLY {
+			return f, err
+		}
+		return blockingCloseFile{File: f, closing: closing, unblock: unblock}, nil
+	}
+
+	f1, _ := pool.Get(fPath1)
+
+	removed := make(chan error)
+	go func() {
+		removed <- pool.Remove(fPath1)
+	}()
+
+	// test1.txt is being closed by Remove, fspool keeps serving others meanwhile
+	<-closing
+	f2, err := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pool.Stats().OpenInstances)
+	assert.ErrorIs(t, pool.Release(f1), ErrFSPoolFilesystemIsNotAcquired)
+
+	close(unblock)
+
+	assert.Nil(t, <-removed)
+	assert.Equal(t, 1, pool.Len())
+	assert.Nil(t, pool.Release(f2))
+}
+
+func TestFSPool_Remove_CouldNotCloseFilesystem(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	fPath := filepath.Join(t.TempDir(), "test.txt")
+
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || flag == os.O_RDONLY {
+			return f, err
+		}
+		return failingCloseFile{f}, nil
+	}
+
+	_, _ = pool.Get(fPath)
+	err := pool.Remove(fPath)
+
+	assert.ErrorIs(t, err, ErrFSPoolCouldNotCloseFilesystem)
+	assert.Equal(t, 0, pool.Len())
+}
+
+func TestFSPool_IdleTimeout_ClosesIdleInstance(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	config.IdleTimeout = 10 * time.Millisecond
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f))
+
+	assert.Eventually(t, func() bool { return pool.Len() == 0 }, time.Second, time.Millisecond)
+
+	rawData, err := os.ReadFile(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_IdleTimeout_KeepsAcquiredInstance(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	config.IdleTimeout = time.Millisecond
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Equal(t, 1, pool.Len())
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f))
+}
+
+func TestFSPool_MaxLifetime_ClosesInstanceOnceReleased(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	config.MaxLifetime = 10 * time.Millisecond
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+
+	time.Sleep(20 * time.Millisecond)
+
+	// acquired instance outlives MaxLifetime until it's released
+	assert.Equal(t, 1, pool.Len())
+	assert.Nil(t, pool.Release(f))
+	assert.Equal(t, 0, pool.Len())
+
+	rawData, err := os.ReadFile(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_Get_ReopensExpiredInstance(t *testing.T) {
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	config.MaxLifetime = time.Hour
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f1, _ := pool.Get(someFilePath)
+	assert.Nil(t, pool.Release(f1))
+
+	// instance is expired but janitor has not closed it yet
+	p := pool.(*fsPool)
+	p.mu.Lock()
+	p.instances[someFilePath].opened = time.Now().Add(-time.Hour)
+	p.mu.Unlock()
+
+	f2, err := pool.Get(someFilePath)
+
+	assert.Nil(t, err)
+	assert.NotSame(t, f1, f2)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestFSPool_MaxLifetime_StopsHandingOutAcquiredInstance(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	config.MaxLifetime = time.Hour
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f1, _ := pool.Get(someFilePath)
+	assert.Nil(t, f1.Write([]byte("fspool"), 0, io.SeekStart))
+
+	// instance is expired while it's acquired
+	p := pool.(*fsPool)
+	p.mu.Lock()
+	p.instances[someFilePath].opened = time.Now().Add(-time.Hour)
+	p.mu.Unlock()
+
+	_, err := pool.Get(someFilePath)
+
+	assert.ErrorIs(t, err, ErrFSPoolLimitReached)
+
+	acquired := make(chan fs.Filesystem)
+	go func() {
+		f, _ := pool.Acquire(context.Background(), someFilePath)
+		acquired <- f
+	}()
+
+	assert.Eventually(t, func() bool { return waitersLen(pool) == 1 }, time.Second, time.Millisecond)
+
+	// a fresh instance is opened once the expired one is released and closed
+	assert.Nil(t, pool.Release(f1))
+
+	f2 := <-acquired
+
+	assert.NotSame(t, f1, f2)
+	assert.Equal(t, 1, pool.Len())
+	assert.Equal(t, map[string]uint64{"max lifetime": 1}, pool.Stats().Evictions)
+
+	rawData, err := f2.ReadData(0, 6, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_MaxLifetime_WaiterOfAcquiredInstanceDoesNotBlockOthers(t *testing.T) {
+	config := newTestConfig(3)
+	config.Eviction = cfgs.EvictLRU
+	config.MaxLifetime = time.Hour
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+
+	f1, _ := pool.Get(fPath1)
+
+	// instance is expired while it's acquired, so its waiter waits until it's released
+	p := pool.(*fsPool)
+	p.mu.Lock()
+	p.instances[fPath1].opened = time.Now().Add(-time.Hour)
+	p.mu.Unlock()
+
+	go func() {
+		_, _ = pool.Acquire(context.Background(), fPath1)
+	}()
+
+	assert.Eventually(t, func() bool { return waitersLen(pool) == 1 }, time.Second, time.Millisecond)
+
+	_, err := pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = pool.Acquire(ctx, filepath.Join(someDirPath, "test3.txt"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, waitersLen(pool))
+	assert.Nil(t, pool.Release(f1))
+}
+
+func TestFSPool_Close(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(2))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f))
+
+	f, _ = pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekEnd))
+
+	// instance is released while fspool is closing
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = pool.Release(f)
+	}()
+
+	err := pool.Close(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pool.Len())
+
+	rawData, err := os.ReadFile(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspoolfspool"), rawData)
+}
+
+func TestFSPool_Close_With_EvictLRU(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f))
+
+	err := pool.Close(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pool.Len())
+
+	rawData, err := os.ReadFile(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_Close_WaitsUntilRelease(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+
+	f, _ := pool.Get(filepath.Join(t.TempDir(), "test.txt"))
+
+	closed := make(chan error)
+	go func() {
+		closed <- pool.Close(context.Background())
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("fspool should not be closed before release")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Nil(t, pool.Release(f))
+	assert.Nil(t, <-closed)
+}
+
+func TestFSPool_Close_ContextIsDone(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := pool.Close(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, pool.Len())
+	assert.ErrorIs(t, pool.Release(f), ErrFSPoolFilesystemIsNotAcquired)
+
+	rawData, err := os.ReadFile(someFilePath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("fspool"), rawData)
+}
+
+func TestFSPool_Close_StopsHandingOutInstances(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f, _ := pool.Get(filepath.Join(someDirPath, "test1.txt"))
+
+	acquired := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test2.txt"))
+		acquired <- err
+	}()
+
+	assert.Eventually(t, func() bool { return waitersLen(pool) == 1 }, time.Second, time.Millisecond)
+
+	closed := make(chan error)
+	go func() {
+		closed <- pool.Close(context.Background())
+	}()
+
+	assert.ErrorIs(t, <-acquired, ErrFSPoolClosed)
+
+	_, err := pool.Get(filepath.Join(someDirPath, "test1.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolClosed)
+
+	_, err = pool.Acquire(context.Background(), filepath.Join(someDirPath, "test1.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolClosed)
+
+	assert.Nil(t, pool.Release(f))
+	assert.Nil(t, <-closed)
+	assert.ErrorIs(t, pool.Close(context.Background()), ErrFSPoolClosed)
+}
+
+type failingCloseFile struct {
+	*os.File
+}
+
+func (f failingCloseFile) Close() error {
+	_ = f.File.Close()
+	return errors.New("some error")
+}
+
+func TestFSPool_Close_CouldNotCloseFilesystem(t *testing.T) {
+	config := newTestConfig(2)
+	config.Perm = cfgs.WOnly
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+	fPath2 := filepath.Join(someDirPath, "test2.txt")
+
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || name != fPath2 {
+			return f, err
+		}
+		return failingCloseFile{f}, nil
+	}
+
+	f1, _ := pool.Get(fPath1)
+	f2, _ := pool.Get(fPath2)
+	assert.Nil(t, pool.Release(f1))
+
+	// f2 is still acquired, so it's closed by force
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := pool.Close(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, ErrFSPoolCouldNotCloseFilesystem)
+	assert.Contains(t, err.Error(), fPath2)
+	assert.NotContains(t, err.Error(), fPath1)
+	assert.Equal(t, 0, pool.Len())
+	assert.ErrorIs(t, pool.Release(f2), ErrFSPoolFilesystemIsNotAcquired)
+}
+
+func TestFSPool_Close_ClosesInstancesWithoutBlockingFSPool(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	fPath := filepath.Join(t.TempDir(), "test.txt")
+
+	syncing := make(chan struct{})
+	unblock := make(chan struct{})
+	pool.(*fsPool).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		f, err := os.OpenFile(name, flag, perm)
+		if err != nil || flag == os.O_RDONLY {
+			return f, err
+		}
+		return blockingSyncFile{File: f, syncing: syncing, unblock: unblock}, nil
+	}
+
+	f, _ := pool.Get(fPath)
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f))
+
+	closed := make(chan error)
+	go func() {
+		closed <- pool.Close(context.Background())
+	}()
+
+	// test.txt is being synced by Close, stats of fspool are served meanwhile
+	<-syncing
+	assert.Equal(t, 0, pool.Stats().OpenInstances)
+
+	close(unblock)
+
+	assert.Nil(t, <-closed)
+	assert.Equal(t, uint64(6), pool.Stats().Total.BytesWritten)
+}
+
+func TestFSPool_Stats(t *testing.T) {
+	config := newTestConfig(2)
+	config.Eviction = cfgs.EvictLRU
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+	fPath1 := filepath.Join(someDirPath, "test1.txt")
+	fPath2 := filepath.Join(someDirPath, "test2.txt")
+
+	f1, _ := pool.Get(fPath1)
+	assert.Nil(t, f1.Write([]byte("fspool"), 0, io.SeekStart))
+	assert.Nil(t, pool.Release(f1))
+
+	f2, _ := pool.Get(fPath2)
+	assert.Nil(t, f2.Write([]byte("fs"), 0, io.SeekStart))
+
+	stats := pool.Stats()
+
+	assert.Equal(t, 2, stats.OpenInstances)
+	assert.Equal(t, 1, stats.IdleInstances)
+	assert.Equal(t, uint64(6), stats.Files[fPath1].BytesWritten)
+	assert.Equal(t, uint64(2), stats.Files[fPath2].Buffered)
+	assert.Equal(t, uint64(8), stats.Total.BytesWritten)
+	assert.Equal(t, uint64(2048), stats.Total.MemoryRent)
+
+	// test1.txt is evicted, but its stats are kept in total
+	_, _ = pool.Get(filepath.Join(someDirPath, "test3.txt"))
+
+	stats = pool.Stats()
+
+	assert.Equal(t, map[string]uint64{"limit": 1}, stats.Evictions)
+	assert.NotContains(t, stats.Files, fPath1)
+	assert.Equal(t, uint64(8), stats.Total.BytesWritten)
+	assert.Equal(t, uint64(1), stats.Total.Flushes)
+	assert.Equal(t, uint64(2048), stats.Total.MemoryRent)
+
+	_, err := pool.Get(filepath.Join(someDirPath, "test4.txt"))
+
+	assert.ErrorIs(t, err, ErrFSPoolLimitReached)
+	assert.Equal(t, uint64(1), pool.Stats().LimitReached)
+}
+
+func TestFSPool_Stats_Waiters(t *testing.T) {
+	pool, _ := NewFSPool(newTestConfig(1))
+	someDirPath := t.TempDir()
+
+	f1, _ := pool.Get(filepath.Join(someDirPath, "test1.txt"))
+
+	acquired := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background(), filepath.Join(someDirPath, "test2.txt"))
+		acquired <- err
+	}()
+
+	assert.Eventually(t, func() bool { return pool.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, pool.Release(f1))
+	assert.Nil(t, <-acquired)
+
+	stats := pool.Stats()
+
+	assert.Equal(t, 0, stats.Waiters)
+	assert.Equal(t, uint64(1), stats.WaitCount)
+	assert.Equal(t, uint64(1), stats.WaitLatency.Count())
+	assert.GreaterOrEqual(t, stats.WaitDuration, 10*time.Millisecond)
+}
+
+func TestFSPool_Hooks(t *testing.T) {
+	var events []string
+
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	config.Hooks = cfgs.Hooks{
+		OnOpen: func(fPath string) {
+			events = append(events, "open "+filepath.Base(fPath))
+		},
+		OnClose: func(fPath string, err error) {
+			events = append(events, "close "+filepath.Base(fPath))
+		},
+		OnEvict: func(fPath string, reason string) {
+			events = append(events, "evict "+filepath.Base(fPath)+" by "+reason)
+		},
+	}
+	pool, _ := NewFSPool(config)
+	someDirPath := t.TempDir()
+
+	f, _ := pool.Get(filepath.Join(someDirPath, "test1.txt"))
+	assert.Nil(t, pool.Release(f))
+
+	_, _ = pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	assert.Equal(t, []string{"open test1.txt", "close test1.txt", "evict test1.txt by limit", "open test2.txt"}, events)
+}
+
+func TestFSPool_Hooks_CallFSPool(t *testing.T) {
+	var lens []int
+	var pool FSPool
+
+	config := newTestConfig(1)
+	config.Eviction = cfgs.EvictLRU
+	config.Hooks = cfgs.Hooks{
+		OnOpen: func(fPath string) {
+			lens = append(lens, pool.Len())
+		},
+		OnClose: func(fPath string, err error) {
+			lens = append(lens, pool.Stats().OpenInstances)
+		},
+		OnEvict: func(fPath string, reason string) {
+			lens = append(lens, pool.Len())
+		},
+	}
+	pool, _ = NewFSPool(config)
+	someDirPath := t.TempDir()
+
+	f, _ := pool.Get(filepath.Join(someDirPath, "test1.txt"))
+	assert.Nil(t, pool.Release(f))
+
+	_, _ = pool.Get(filepath.Join(someDirPath, "test2.txt"))
+
+	// evicted instance keeps its place until it's closed
+	assert.Equal(t, []int{1, 1, 1, 1}, lens)
+}
+
+func TestFSPool_Append_Concurrent(t *testing.T) {
+	config := newTestConfig(1)
+	config.FlushSize = 64
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("header"), 0, io.SeekStart))
+
+	offsets := make([]int64, 50)
+	var wg sync.WaitGroup
+
+	for i := range offsets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			offset, err := f.Append([]byte(fmt.Sprintf("%03d", i)))
+			assert.Nil(t, err)
+			offsets[i] = offset
+		}(i)
+	}
+
+	wg.Wait()
+
+	rawData, err := f.ReadAllData()
+
+	assert.Nil(t, err)
+	assert.Len(t, rawData, 6+3*len(offsets))
+
+	for i, offset := range offsets {
+		assert.Equal(t, fmt.Sprintf("%03d", i), string(rawData[offset:offset+3]))
+	}
+}
+
+func TestFSPool_ReplaceAll(t *testing.T) {
+	config := newTestConfig(1)
+	config.ReaderLimit = 2
+	pool, _ := NewFSPool(config)
+	someFilePath := filepath.Join(t.TempDir(), "test.txt")
+
+	f, _ := pool.Get(someFilePath)
+	assert.Nil(t, f.Write([]byte("previous"), 0, io.SeekStart))
+	assert.Nil(t, f.Sync())
+
+	// acquired reader keeps reading the previous content until it's released
+	r, _ := f.AcquireReader()
+
+	assert.Nil(t, f.ReplaceAll([]byte("next")))
+
+	previous, err := r.ReadAllData()
+	assert.Nil(t, err)
+	assert.Equal(t, "previous", string(previous))
+	assert.Nil(t, f.ReleaseReader(r))
+
+	next, err := f.ReadAllData()
+	assert.Nil(t, err)
+	assert.Equal(t, "next", string(next))
+
+	onDisk, err := os.ReadFile(someFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "next", string(onDisk))
+
+	// writer continues on the new file and temporary files are not left behind
+	_, err = f.Append([]byte("!"))
+	assert.Nil(t, err)
+	assert.Nil(t, f.Sync())
+
+	onDisk, _ = os.ReadFile(someFilePath)
+	assert.Equal(t, "next!", string(onDisk))
+
+	entries, _ := os.ReadDir(filepath.Dir(someFilePath))
+	assert.Len(t, entries, 1)
+}
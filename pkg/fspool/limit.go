@@ -0,0 +1,70 @@
+package fspool
+
+import (
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fspoolConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fspool"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"math"
+)
+
+var (
+	ErrFSPoolCouldNotGetFileLimit   = errors.New("package fspool - could not get file descriptor limit of process")
+	ErrFSPoolReaderLimitIsUnlimited = errors.New("package fspool - limit of fspool can't be derived from file descriptor limit while reader limit is unlimited")
+)
+
+// Budget describes how limit of fspool has been sized
+type Budget struct {
+	Auto                   bool   // true means limit has been derived from RLIMIT_NOFILE
+	FileLimit              uint64 // soft RLIMIT_NOFILE of process (0 if limit is defined by configuration)
+	Headroom               uint64 // file descriptors which are left for the rest of process
+	DescriptorsPerInstance uint64 // file descriptors which each filesystem instance may open
+	Limit                  uint32 // limit of filesystem instances
+}
+
+// newBudget returns budget of fspool, limit of configuration is used as is and zero limit is derived from file descriptor limit of process
+func newBudget(config fspoolConfig.FSPoolConfiguration, fileLimitFunc func() (uint64, error)) (Budget, error) {
+	b := Budget{
+		DescriptorsPerInstance: config.DescriptorsPerInstance(),
+		Limit:                  config.Limit,
+	}
+
+	if config.Limit != 0 {
+		return b, nil
+	}
+
+	// descriptors of unlimited readers can't be budgeted
+	if config.ReaderLimit == 0 && config.Perm != cfgs.WOnly {
+		return Budget{}, ErrFSPoolReaderLimitIsUnlimited
+	}
+
+	fileLimit, err := fileLimitFunc()
+	if err != nil {
+		return Budget{}, errs.New("getrlimit", "", errs.NoOffset, ErrFSPoolCouldNotGetFileLimit, err)
+	}
+
+	b.Auto = true
+	b.FileLimit = fileLimit
+	b.Headroom = uint64(config.FDHeadroom)
+	if b.Headroom == 0 {
+		b.Headroom = uint64(fspoolConfig.DefaultFDHeadroom)
+	}
+
+	if fileLimit <= b.Headroom {
+		return Budget{}, ErrFSPoolLimitIsZero
+	}
+
+	limit := (fileLimit - b.Headroom) / b.DescriptorsPerInstance
+	if limit == 0 {
+		return Budget{}, ErrFSPoolLimitIsZero
+	}
+
+	// RLIMIT_NOFILE may be unlimited
+	if limit > math.MaxUint32 {
+		limit = math.MaxUint32
+	}
+
+	b.Limit = uint32(limit)
+
+	return b, nil
+}
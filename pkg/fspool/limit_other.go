@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fspool
+
+import "errors"
+
+// fileLimit returns soft RLIMIT_NOFILE of process, it's not supported on this platform so limit of fspool should be defined
+func fileLimit() (uint64, error) {
+	return 0, errors.New("RLIMIT_NOFILE is not supported on this platform")
+}
@@ -0,0 +1,94 @@
+package fspool
+
+import (
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func fileLimitOf(n uint64) func() (uint64, error) {
+	return func() (uint64, error) {
+		return n, nil
+	}
+}
+
+func TestNewBudget_DefinedLimit(t *testing.T) {
+	budget, err := newBudget(newTestConfig(10), func() (uint64, error) {
+		t.Fatal("file limit should not be read")
+		return 0, nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, Budget{DescriptorsPerInstance: 3, Limit: 10}, budget)
+}
+
+func TestNewBudget_AutoLimit(t *testing.T) {
+	config := newTestConfig(0)
+	config.ReaderLimit = 3
+
+	budget, err := newBudget(config, fileLimitOf(1024))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Budget{Auto: true, FileLimit: 1024, Headroom: 64, DescriptorsPerInstance: 5, Limit: 192}, budget)
+}
+
+func TestNewBudget_AutoLimit_FDHeadroom(t *testing.T) {
+	config := newTestConfig(0)
+	config.Perm = cfgs.WOnly
+	config.FDHeadroom = 24
+
+	budget, err := newBudget(config, fileLimitOf(1024))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(24), budget.Headroom)
+	assert.Equal(t, uint32(500), budget.Limit)
+}
+
+func TestNewBudget_AutoLimit_UnlimitedReaders(t *testing.T) {
+	config := newTestConfig(0)
+	config.Perm = cfgs.ROnly
+	config.ReaderLimit = 0
+
+	_, err := newBudget(config, fileLimitOf(1024))
+
+	assert.ErrorIs(t, err, ErrFSPoolReaderLimitIsUnlimited)
+}
+
+func TestNewBudget_AutoLimit_WOnly_UnlimitedReaders(t *testing.T) {
+	config := newTestConfig(0)
+	config.Perm = cfgs.WOnly
+	config.ReaderLimit = 0
+
+	// write only instances don't have any reader
+	budget, err := newBudget(config, fileLimitOf(1024))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), budget.DescriptorsPerInstance)
+	assert.Equal(t, uint32(480), budget.Limit)
+}
+
+func TestNewBudget_AutoLimit_Unlimited(t *testing.T) {
+	budget, err := newBudget(newTestConfig(0), fileLimitOf(math.MaxUint64))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(math.MaxUint32), budget.Limit)
+}
+
+func TestNewBudget_AutoLimit_HeadroomExceedsFileLimit(t *testing.T) {
+	_, err := newBudget(newTestConfig(0), fileLimitOf(64))
+
+	assert.ErrorIs(t, err, ErrFSPoolLimitIsZero)
+}
+
+func TestNewBudget_AutoLimit_CouldNotGetFileLimit(t *testing.T) {
+	someErr := errors.New("some error")
+
+	_, err := newBudget(newTestConfig(0), func() (uint64, error) {
+		return 0, someErr
+	})
+
+	assert.ErrorIs(t, err, ErrFSPoolCouldNotGetFileLimit)
+	assert.ErrorIs(t, err, someErr)
+}
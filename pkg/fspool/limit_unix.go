@@ -0,0 +1,15 @@
+//go:build unix
+
+package fspool
+
+import "syscall"
+
+// fileLimit returns soft RLIMIT_NOFILE of process
+func fileLimit() (uint64, error) {
+	var rLimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit); err != nil {
+		return 0, err
+	}
+
+	return uint64(rLimit.Cur), nil
+}
@@ -0,0 +1,35 @@
+// Package logger contains logger interface which fspool reports errors, flushes, evictions and slow operations to
+package logger
+
+/*
+* Logger receives messages of fspool with key-value pairs as args, like ("path", "/test/test.txt", "err", err)
+* Tip: *slog.Logger of golang satisfies Logger, so you can pass it directly
+ */
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type nopLogger struct{}
+
+// Nop is a Logger which drops every message, it's default logger of fspool
+var Nop Logger = nopLogger{}
+
+// OrNop returns l, or Nop if l is nil
+func OrNop(l Logger) Logger {
+	if l == nil {
+		return Nop
+	}
+
+	return l
+}
+
+func (nopLogger) Debug(string, ...any) {}
+
+func (nopLogger) Info(string, ...any) {}
+
+func (nopLogger) Warn(string, ...any) {}
+
+func (nopLogger) Error(string, ...any) {}
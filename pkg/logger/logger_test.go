@@ -0,0 +1,17 @@
+package logger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type someLogger struct {
+	nopLogger
+}
+
+func TestOrNop(t *testing.T) {
+	assert.Equal(t, Nop, OrNop(nil))
+
+	l := &someLogger{}
+	assert.Equal(t, l, OrNop(l))
+}
@@ -0,0 +1,130 @@
+// Package metrics exposes stats of fspool in text exposition format of Prometheus, it doesn't depend on any Prometheus library
+package metrics
+
+import (
+	"fmt"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"github.com/amirvalhalla/fspool/pkg/fspool"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ContentType is content type of text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns http.Handler which serves stats of pool in text exposition format
+func Handler(pool fspool.FSPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = WriteText(w, pool.Stats())
+	})
+}
+
+// WriteText writes stats of fspool into w in text exposition format
+func WriteText(w io.Writer, stats fspool.Stats) error {
+	e := &encoder{w: w}
+
+	e.gauge("fspool_open_instances", "Live filesystem instances of fspool.", float64(stats.OpenInstances))
+	e.gauge("fspool_idle_instances", "Filesystem instances which are kept open by eviction policy without any holder.", float64(stats.IdleInstances))
+	e.gauge("fspool_waiters", "Callers of Acquire which are waiting for a place in fspool.", float64(stats.Waiters))
+	e.gauge("fspool_limit", "Limit of filesystem instances of fspool.", float64(stats.Budget.Limit))
+	e.histogram("fspool_acquire_wait_seconds", "Waiting of callers of Acquire for a place in fspool.", stats.WaitLatency, stats.WaitDuration)
+	e.counter("fspool_limit_reached_total", "Calls of Get which have failed because fspool has reached its limit.", float64(stats.LimitReached))
+	e.labeledCounter("fspool_evictions_total", "Idle filesystem instances which have been closed by reason.", "reason", stats.Evictions)
+
+	total := stats.Total
+
+	e.labeledCounter("fspool_operations_total", "Successful reads and writes of filesystem instances.", "op", map[string]uint64{
+		"read":  total.Reads,
+		"write": total.Writes,
+	})
+	e.labeledCounter("fspool_bytes_total", "Bytes which have been read or written by filesystem instances.", "direction", map[string]uint64{
+		"read":    total.BytesRead,
+		"written": total.BytesWritten,
+	})
+	e.histogram("fspool_flush_seconds", "Flushes of memory rent into file.", total.FlushLatency, total.FlushDuration)
+	e.gauge("fspool_flush_max_seconds", "The slowest flush of memory rent into file.", total.MaxFlushDuration.Seconds())
+	e.gauge("fspool_buffered_bytes", "Bytes which are kept in memory rent of live filesystem instances.", float64(total.Buffered))
+	e.gauge("fspool_memory_rent_bytes", "Memory rent of live filesystem instances.", float64(total.MemoryRent))
+	e.labeledCounter("fspool_errors_total", "Failed operations of filesystem instances by kind of operation.", "op", total.Errors)
+
+	return e.err
+}
+
+// encoder writes metrics in text exposition format and keeps the first error of w
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+// printf writes formatted text into w unless a previous write has failed
+func (e *encoder) printf(format string, args ...any) {
+	if e.err != nil {
+		return
+	}
+
+	_, e.err = fmt.Fprintf(e.w, format, args...)
+}
+
+// header writes HELP & TYPE lines of metric
+func (e *encoder) header(name string, help string, typ string) {
+	e.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// gauge writes a metric whose value can go up and down
+func (e *encoder) gauge(name string, help string, value float64) {
+	e.header(name, help, "gauge")
+	e.printf("%s %s\n", name, formatValue(value))
+}
+
+// counter writes a metric whose value only goes up
+func (e *encoder) counter(name string, help string, value float64) {
+	e.header(name, help, "counter")
+	e.printf("%s %s\n", name, formatValue(value))
+}
+
+// labeledCounter writes a sample for each value ordered by its label, so output is stable
+func (e *encoder) labeledCounter(name string, help string, label string, values map[string]uint64) {
+	e.header(name, help, "counter")
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		e.printf("%s{%s=\"%s\"} %d\n", name, label, escapeLabel(k), values[k])
+	}
+}
+
+// histogram writes cumulative buckets of latencies in seconds by fs.LatencyBuckets with their sum & count
+func (e *encoder) histogram(name string, help string, h fs.Histogram, sum time.Duration) {
+	e.header(name, help, "histogram")
+
+	var cumulative uint64
+	for i, bound := range fs.LatencyBuckets {
+		cumulative += h.Counts[i]
+		e.printf("%s_bucket{le=\"%s\"} %d\n", name, formatValue(bound.Seconds()), cumulative)
+	}
+
+	count := h.Count()
+	e.printf("%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	e.printf("%s_sum %s\n%s_count %d\n", name, formatValue(sum.Seconds()), name, count)
+}
+
+// formatValue formats value of sample
+func formatValue(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+// labelReplacer escapes backslash, double quote and line feed of label values
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes label value of sample
+func escapeLabel(v string) string {
+	return labelReplacer.Replace(v)
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fspoolConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fspool"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"github.com/amirvalhalla/fspool/pkg/fspool"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	stats := fspool.Stats{
+		OpenInstances: 2,
+		IdleInstances: 1,
+		Waiters:       3,
+		WaitCount:     4,
+		WaitDuration:  1500 * time.Millisecond,
+		WaitLatency:   fs.Histogram{Counts: [11]uint64{7: 3, 8: 1}},
+		LimitReached:  5,
+		Evictions:     map[string]uint64{"limit": 2, "idle timeout": 1},
+		Budget:        fspool.Budget{Limit: 10},
+		Total: fs.Stats{
+			Writes:           6,
+			BytesWritten:     60,
+			Reads:            7,
+			BytesRead:        70,
+			Flushes:          2,
+			FlushDuration:    250 * time.Millisecond,
+			MaxFlushDuration: 200 * time.Millisecond,
+			FlushLatency:     fs.Histogram{Counts: [11]uint64{5: 1, 7: 1}},
+			Buffered:         512,
+			MemoryRent:       2048,
+			Errors:           map[string]uint64{"write": 1, "sync": 2},
+		},
+	}
+
+	var buff bytes.Buffer
+	err := WriteText(&buff, stats)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `# HELP fspool_open_instances Live filesystem instances of fspool.
+# TYPE fspool_open_instances gauge
+fspool_open_instances 2
+# HELP fspool_idle_instances Filesystem instances which are kept open by eviction policy without any holder.
+# TYPE fspool_idle_instances gauge
+fspool_idle_instances 1
+# HELP fspool_waiters Callers of Acquire which are waiting for a place in fspool.
+# TYPE fspool_waiters gauge
+fspool_waiters 3
+# HELP fspool_limit Limit of filesystem instances of fspool.
+# TYPE fspool_limit gauge
+fspool_limit 10
+# HELP fspool_acquire_wait_seconds Waiting of callers of Acquire for a place in fspool.
+# TYPE fspool_acquire_wait_seconds histogram
+fspool_acquire_wait_seconds_bucket{le="0.0001"} 0
+fspool_acquire_wait_seconds_bucket{le="0.0005"} 0
+fspool_acquire_wait_seconds_bucket{le="0.001"} 0
+fspool_acquire_wait_seconds_bucket{le="0.005"} 0
+fspool_acquire_wait_seconds_bucket{le="0.01"} 0
+fspool_acquire_wait_seconds_bucket{le="0.05"} 0
+fspool_acquire_wait_seconds_bucket{le="0.1"} 0
+fspool_acquire_wait_seconds_bucket{le="0.5"} 3
+fspool_acquire_wait_seconds_bucket{le="1"} 4
+fspool_acquire_wait_seconds_bucket{le="5"} 4
+fspool_acquire_wait_seconds_bucket{le="+Inf"} 4
+fspool_acquire_wait_seconds_sum 1.5
+fspool_acquire_wait_seconds_count 4
+# HELP fspool_limit_reached_total Calls of Get which have failed because fspool has reached its limit.
+# TYPE fspool_limit_reached_total counter
+fspool_limit_reached_total 5
+# HELP fspool_evictions_total Idle filesystem instances which have been closed by reason.
+# TYPE fspool_evictions_total counter
+fspool_evictions_total{reason="idle timeout"} 1
+fspool_evictions_total{reason="limit"} 2
+# HELP fspool_operations_total Successful reads and writes of filesystem instances.
+# TYPE fspool_operations_total counter
+fspool_operations_total{op="read"} 7
+fspool_operations_total{op="write"} 6
+# HELP fspool_bytes_total Bytes which have been read or written by filesystem instances.
+# TYPE fspool_bytes_total counter
+fspool_bytes_total{direction="read"} 70
+fspool_bytes_total{direction="written"} 60
+# HELP fspool_flush_seconds Flushes of memory rent into file.
+# TYPE fspool_flush_seconds histogram
+fspool_flush_seconds_bucket{le="0.0001"} 0
+fspool_flush_seconds_bucket{le="0.0005"} 0
+fspool_flush_seconds_bucket{le="0.001"} 0
+fspool_flush_seconds_bucket{le="0.005"} 0
+fspool_flush_seconds_bucket{le="0.01"} 0
+fspool_flush_seconds_bucket{le="0.05"} 1
+fspool_flush_seconds_bucket{le="0.1"} 1
+fspool_flush_seconds_bucket{le="0.5"} 2
+fspool_flush_seconds_bucket{le="1"} 2
+fspool_flush_seconds_bucket{le="5"} 2
+fspool_flush_seconds_bucket{le="+Inf"} 2
+fspool_flush_seconds_sum 0.25
+fspool_flush_seconds_count 2
+# HELP fspool_flush_max_seconds The slowest flush of memory rent into file.
+# TYPE fspool_flush_max_seconds gauge
+fspool_flush_max_seconds 0.2
+# HELP fspool_buffered_bytes Bytes which are kept in memory rent of live filesystem instances.
+# TYPE fspool_buffered_bytes gauge
+fspool_buffered_bytes 512
+# HELP fspool_memory_rent_bytes Memory rent of live filesystem instances.
+# TYPE fspool_memory_rent_bytes gauge
+fspool_memory_rent_bytes 2048
+# HELP fspool_errors_total Failed operations of filesystem instances by kind of operation.
+# TYPE fspool_errors_total counter
+fspool_errors_total{op="sync"} 2
+fspool_errors_total{op="write"} 1
+`, buff.String())
+}
+
+func TestWriteText_EscapesLabel(t *testing.T) {
+	var buff bytes.Buffer
+	err := WriteText(&buff, fspool.Stats{Evictions: map[string]uint64{"some \"reason\"\n": 1}})
+
+	assert.Nil(t, err)
+	assert.Contains(t, buff.String(), `fspool_evictions_total{reason="some \"reason\"\n"} 1`)
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("some error")
+}
+
+func TestWriteText_CouldNotWrite(t *testing.T) {
+	w := &failingWriter{}
+
+	err := WriteText(w, fspool.Stats{})
+
+	assert.EqualError(t, err, "some error")
+	assert.Equal(t, 1, w.writes)
+}
+
+func TestHandler(t *testing.T) {
+	pool, _ := fspool.NewFSPool(fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       1,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	})
+
+	f, _ := pool.Get(filepath.Join(t.TempDir(), "test.txt"))
+	assert.Nil(t, f.Write([]byte("fspool"), 0, io.SeekStart))
+
+	server := httptest.NewServer(Handler(pool))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "fspool_open_instances 1\n")
+	assert.Contains(t, string(body), "fspool_bytes_total{direction=\"written\"} 6\n")
+	assert.Contains(t, string(body), "fspool_buffered_bytes 6\n")
+}
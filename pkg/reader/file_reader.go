@@ -3,9 +3,13 @@ package reader
 
 import (
 	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	"github.com/amirvalhalla/fspool/pkg/errs"
 	"github.com/amirvalhalla/fspool/pkg/file"
 	"github.com/google/uuid"
+	"io"
 	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -17,16 +21,19 @@ var (
 )
 
 type fileReader struct {
-	id    uuid.UUID
-	rFile file.File
-	rwMu  sync.RWMutex
+	id     uuid.UUID
+	rFile  file.File
+	ioMode cfgs.IOMode
+	pos    atomic.Int64 // offset of file which next read continues from in PositionalIO mode (used by io.SeekCurrent)
+	rwMu   sync.RWMutex
 }
 
 // FileReader interface gives you some options for reading from a file
 type FileReader interface {
 	// ReadData func provides reading data from file by defining custom pos & seek option
+	// it returns io.EOF if nothing could be read and io.ErrUnexpectedEOF with read bytes if file ends before len
 	ReadData(offset int64, len int, seek int) ([]byte, error)
-	// ReadAllData func provides reading all data from file
+	// ReadAllData func provides reading all data from file from its beginning
 	ReadAllData() ([]byte, error)
 	// GetId return id of FileReader
 	GetId() uuid.UUID
@@ -35,52 +42,78 @@ type FileReader interface {
 }
 
 // NewFileReader func provides new instance of FileReader interface with unique memory addresses of its objects
-func NewFileReader(file file.File) (FileReader, uuid.UUID) {
+// ioMode defines whether reader uses ReadAt (safe for concurrent reads) or Seek followed by Read, failures are returned to caller without logging
+func NewFileReader(file file.File, ioMode cfgs.IOMode) (FileReader, uuid.UUID) {
 	id := uuid.New()
 
 	return &fileReader{
-		id:    id,
-		rFile: file,
+		id:     id,
+		rFile:  file,
+		ioMode: ioMode,
 	}, id
 }
 
 // ReadData func provides reading data from file by defining custom pos & seek option
+// it returns exactly the bytes which have been read, io.EOF means nothing could be read and io.ErrUnexpectedEOF means less than len bytes have been read
 func (r *fileReader) ReadData(offset int64, len int, seek int) ([]byte, error) {
-	r.rwMu.RLock()
-	defer r.rwMu.RUnlock()
+	if r.ioMode == cfgs.PositionalIO {
+		return r.readDataAt(offset, len, seek)
+	}
+
+	// seek and read share offset of file, so they must not be interleaved with other reads
+	r.rwMu.Lock()
+	defer r.rwMu.Unlock()
 
 	buff := make([]byte, len)
 
-	if _, err := r.rFile.Seek(offset, seek); err != nil {
-		return nil, ErrFileReaderCouldNotSeek
+	pos, err := r.rFile.Seek(offset, seek)
+	if err != nil {
+		return nil, errs.New("seek", "", offset, ErrFileReaderCouldNotSeek, err)
 	}
 
-	if _, err := r.rFile.Read(buff); err != nil {
-		return nil, ErrFileReaderCouldNotRead
-	}
+	n, err := io.ReadFull(r.rFile, buff)
 
-	return buff, nil
+	return r.readResult(buff, n, pos, err)
 }
 
-// ReadAllData func provides reading all data from file
+// ReadAllData func provides reading all data from file, it always reads from the beginning of file
 func (r *fileReader) ReadAllData() ([]byte, error) {
-	r.rwMu.RLock()
-	defer r.rwMu.RUnlock()
+	if r.ioMode == cfgs.PositionalIO {
+		r.rwMu.RLock()
+		defer r.rwMu.RUnlock()
+	} else {
+		r.rwMu.Lock()
+		defer r.rwMu.Unlock()
+	}
 
 	var buffSize int64 = 0
 
 	if fInfo, err := r.rFile.Stat(); err != nil {
-		return nil, ErrFileReaderCouldNotGetFileStat
+		return nil, errs.New("stat", "", errs.NoOffset, ErrFileReaderCouldNotGetFileStat, err)
 	} else {
 		buffSize = fInfo.Size()
 	}
 
 	buff := make([]byte, buffSize)
-	if _, err := r.rFile.Read(buff); err != nil {
-		return nil, ErrFileReaderCouldNotReadAllData
+
+	var n int
+	var err error
+
+	if r.ioMode == cfgs.PositionalIO {
+		n, err = readFullAt(r.rFile, buff, 0)
+	} else {
+		if _, err := r.rFile.Seek(0, io.SeekStart); err != nil {
+			return nil, errs.New("seek", "", 0, ErrFileReaderCouldNotSeek, err)
+		}
+		n, err = io.ReadFull(r.rFile, buff)
 	}
 
-	return buff, nil
+	// file may be truncated after getting its stat, so reaching end of file sooner is not an error here
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errs.New("read", "", int64(n), ErrFileReaderCouldNotReadAllData, err)
+	}
+
+	return buff[:n], nil
 }
 
 // GetId return id of FileReader
@@ -90,12 +123,96 @@ func (r *fileReader) GetId() uuid.UUID {
 
 // Close func provides close reader instance
 func (r *fileReader) Close() error {
-	r.rwMu.RLock()
-	defer r.rwMu.RUnlock()
+	r.rwMu.Lock()
+	defer r.rwMu.Unlock()
 
 	if err := r.rFile.Close(); err != nil {
-		return ErrFileReaderCouldNotClose
+		return errs.New("close", "", errs.NoOffset, ErrFileReaderCouldNotClose, err)
 	}
 
 	return nil
 }
+
+// readDataAt reads data by ReadAt without touching offset of file, so concurrent callers don't race on it
+func (r *fileReader) readDataAt(offset int64, len int, seek int) ([]byte, error) {
+	r.rwMu.RLock()
+	defer r.rwMu.RUnlock()
+
+	pos, err := r.resolveOffset(offset, seek)
+	if err != nil {
+		return nil, err
+	}
+
+	buff := make([]byte, len)
+
+	n, err := readFullAt(r.rFile, buff, pos)
+	r.pos.Store(pos + int64(n))
+
+	return r.readResult(buff, n, pos, err)
+}
+
+// resolveOffset converts offset & seek option into offset of file in PositionalIO mode
+func (r *fileReader) resolveOffset(offset int64, seek int) (int64, error) {
+	var pos int64
+
+	switch seek {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos.Load() + offset
+	case io.SeekEnd:
+		fInfo, err := r.rFile.Stat()
+		if err != nil {
+			return 0, errs.New("seek", "", offset, ErrFileReaderCouldNotSeek, err)
+		}
+		pos = fInfo.Size() + offset
+	default:
+		return 0, ErrFileReaderCouldNotSeek
+	}
+
+	if pos < 0 {
+		return 0, ErrFileReaderCouldNotSeek
+	}
+
+	return pos, nil
+}
+
+// readFullAt reads exactly len(buff) bytes from offset of file by ReadAt, it reports errors like io.ReadFull does
+func readFullAt(rFile io.ReaderAt, buff []byte, offset int64) (int, error) {
+	var n int
+
+	for n < len(buff) {
+		nn, err := rFile.ReadAt(buff[n:], offset+int64(n))
+		n += nn
+
+		if err != nil {
+			if errors.Is(err, io.EOF) && n > 0 && n < len(buff) {
+				return n, io.ErrUnexpectedEOF
+			}
+			if errors.Is(err, io.EOF) && n == len(buff) {
+				return n, nil
+			}
+			return n, err
+		}
+
+		if nn == 0 {
+			return n, io.ErrNoProgress
+		}
+	}
+
+	return n, nil
+}
+
+// readResult returns bytes which have been read from pos, io.EOF and io.ErrUnexpectedEOF are kept so callers can tell them apart from other failures
+func (r *fileReader) readResult(buff []byte, n int, pos int64, err error) ([]byte, error) {
+	switch {
+	case err == nil:
+		return buff[:n], nil
+	case errors.Is(err, io.EOF):
+		return buff[:0], io.EOF
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return buff[:n], io.ErrUnexpectedEOF
+	default:
+		return nil, errs.New("read", "", pos+int64(n), ErrFileReaderCouldNotRead, err)
+	}
+}
@@ -2,6 +2,7 @@ package reader
 
 import (
 	mockfile "github.com/amirvalhalla/fspool/mocks/file"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ func TestNewFileReader(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	assert.NotNil(t, fReader)
 }
@@ -24,12 +25,12 @@ func TestFileReader_ReadData(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).Times(1)
+	mockFile.EXPECT().Read([]byte{0}).Return(1, nil).Times(1)
 
-	_, err := fReader.ReadData(0, 0, io.SeekStart)
+	_, err := fReader.ReadData(0, 1, io.SeekStart)
 
 	assert.Nil(t, err)
 }
@@ -39,13 +40,13 @@ func TestFileReader_ReadData_CouldNotSeek(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), ErrFileReaderCouldNotSeek).Times(1)
 
 	_, err := fReader.ReadData(0, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFileReaderCouldNotSeek.Error())
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotSeek)
 }
 
 func TestFileReader_ReadData_CouldNotRead(t *testing.T) {
@@ -53,14 +54,14 @@ func TestFileReader_ReadData_CouldNotRead(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), nil).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, ErrFileReaderCouldNotRead).Times(1)
+	mockFile.EXPECT().Read([]byte{0}).Return(0, ErrFileReaderCouldNotRead).Times(1)
 
-	_, err := fReader.ReadData(0, 0, io.SeekStart)
+	_, err := fReader.ReadData(0, 1, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFileReaderCouldNotRead.Error())
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotRead)
 }
 
 func TestFileReader_ReadAllData(t *testing.T) {
@@ -69,11 +70,12 @@ func TestFileReader_ReadAllData(t *testing.T) {
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
-	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(1)).Times(1)
+	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
+	mockFile.EXPECT().Read([]byte{0}).Return(1, nil).Times(1)
 
 	_, err := fReader.ReadAllData()
 
@@ -85,13 +87,13 @@ func TestFileReader_ReadAllData_CouldNotGetFileStat(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Stat().Return(nil, ErrFileReaderCouldNotGetFileStat).Times(1)
 
 	_, err := fReader.ReadAllData()
 
-	assert.EqualError(t, err, ErrFileReaderCouldNotGetFileStat.Error())
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotGetFileStat)
 }
 
 func TestFileReader_ReadAllData_CouldNotReadAllData(t *testing.T) {
@@ -100,15 +102,16 @@ func TestFileReader_ReadAllData_CouldNotReadAllData(t *testing.T) {
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
-	mockFileInfo.EXPECT().Size().Return(int64(0)).Times(1)
-	mockFile.EXPECT().Read([]byte{}).Return(0, ErrFileReaderCouldNotReadAllData).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(1)).Times(1)
+	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
+	mockFile.EXPECT().Read([]byte{0}).Return(0, ErrFileReaderCouldNotReadAllData).Times(1)
 
 	_, err := fReader.ReadAllData()
 
-	assert.EqualError(t, err, ErrFileReaderCouldNotReadAllData.Error())
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotReadAllData)
 }
 
 func TestFileReader_GetId(t *testing.T) {
@@ -116,7 +119,7 @@ func TestFileReader_GetId(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	id := fReader.GetId()
 
@@ -129,7 +132,7 @@ func TestFileReader_Close(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Close().Return(nil).Times(1)
 
@@ -143,11 +146,170 @@ func TestFileReader_Close_CouldNotClose(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fReader, _ := NewFileReader(mockFile)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
 
 	mockFile.EXPECT().Close().Return(ErrFileReaderCouldNotClose).Times(1)
 
 	err := fReader.Close()
 
-	assert.EqualError(t, err, ErrFileReaderCouldNotClose.Error())
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotClose)
+}
+
+func TestFileReader_ReadData_PositionalIO(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	gomock.InOrder(
+		mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(4)).Return(2, nil).Times(1),
+		mockFile.EXPECT().ReadAt([]byte{0}, int64(7)).Return(1, nil).Times(1),
+	)
+
+	_, err := fReader.ReadData(4, 2, io.SeekStart)
+	assert.Nil(t, err)
+
+	// io.SeekCurrent continues from the end of previous read
+	_, err = fReader.ReadData(1, 1, io.SeekCurrent)
+	assert.Nil(t, err)
+}
+
+func TestFileReader_ReadData_PositionalIO_SeekEnd(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(10)).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(8)).Return(2, nil).Times(1)
+
+	_, err := fReader.ReadData(-2, 2, io.SeekEnd)
+
+	assert.Nil(t, err)
+}
+
+func TestFileReader_ReadData_PositionalIO_CouldNotSeek(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	_, err := fReader.ReadData(-1, 1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotSeek)
+}
+
+func TestFileReader_ReadData_PositionalIO_CouldNotRead(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().ReadAt([]byte{0}, int64(0)).Return(0, ErrFileReaderCouldNotRead).Times(1)
+
+	_, err := fReader.ReadData(0, 1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFileReaderCouldNotRead)
+}
+
+func TestFileReader_ReadAllData_PositionalIO(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(2)).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(0)).Return(2, nil).Times(1)
+
+	_, err := fReader.ReadAllData()
+
+	assert.Nil(t, err)
+}
+
+func TestFileReader_ReadData_EOF(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().ReadAt([]byte{0, 0}, int64(10)).Return(0, io.EOF).Times(1)
+
+	rawData, err := fReader.ReadData(10, 2, io.SeekStart)
+
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Empty(t, rawData)
+}
+
+func TestFileReader_ReadData_UnexpectedEOF(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().ReadAt([]byte{0, 0, 0}, int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		copy(p, []byte{1, 2})
+		return 2, io.EOF
+	}).Times(1)
+
+	rawData, err := fReader.ReadData(0, 3, io.SeekStart)
+
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, []byte{1, 2}, rawData)
+}
+
+func TestFileReader_ReadData_ShortRead(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.SeekIO)
+
+	mockFile.EXPECT().Seek(int64(0), io.SeekStart).Return(int64(0), nil).Times(1)
+	gomock.InOrder(
+		mockFile.EXPECT().Read([]byte{0, 0, 0}).DoAndReturn(func(p []byte) (int, error) {
+			copy(p, []byte{1, 2})
+			return 2, nil
+		}).Times(1),
+		mockFile.EXPECT().Read([]byte{0}).DoAndReturn(func(p []byte) (int, error) {
+			p[0] = 3
+			return 1, nil
+		}).Times(1),
+	)
+
+	rawData, err := fReader.ReadData(0, 3, io.SeekStart)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, rawData)
+}
+
+func TestFileReader_ReadAllData_FileTruncated(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	fReader, _ := NewFileReader(mockFile, cfgs.PositionalIO)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(3)).Times(1)
+	mockFile.EXPECT().ReadAt([]byte{0, 0, 0}, int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+		p[0] = 1
+		return 1, io.EOF
+	}).Times(1)
+
+	rawData, err := fReader.ReadAllData()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1}, rawData)
 }
@@ -0,0 +1,214 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"io"
+)
+
+// Iterator reads records of wal in order of their LSN
+type Iterator interface {
+	// Next moves iterator to the next record, it returns false once there isn't any record or reading fails
+	// Next can be called again after it has returned false without any error to read records which have been appended meanwhile
+	Next() bool
+	// Record returns record which iterator points to after Next has returned true
+	Record() Record
+	// Err returns error which has stopped iterator, nil means all records have been read
+	Err() error
+	// Close gives back segment which iterator is reading to fspool
+	Close() error
+}
+
+type iterator struct {
+	w        *wal
+	seg      fs.Filesystem // segment which iterator is reading, nil means it should be opened
+	base     uint64        // LSN of first record of segment
+	nextBase uint64        // LSN of first record of the next segment, 0 means segment is current segment of wal
+	offset   int64         // offset of segment which the next record starts at
+	limit    int64         // size of records of current segment of wal which can be read, -1 means segment is read until its end
+	lsn      uint64        // LSN of the next record
+	from     uint64        // records before it are skipped
+	rec      Record
+	err      error
+	closed   bool
+}
+
+// Next moves iterator to the next record, it returns false once there isn't any record or reading fails
+func (it *iterator) Next() bool {
+	if it.closed {
+		return false
+	}
+
+	for it.err == nil {
+		if it.seg == nil {
+			if it.err = it.open(); it.err != nil {
+				return false
+			}
+		}
+
+		// records beyond limit of current segment are still being written
+		if it.limit >= 0 && it.offset >= it.limit {
+			if it.err = it.refresh(); it.err != nil {
+				return false
+			}
+
+			if it.limit >= 0 && it.offset >= it.limit {
+				return false
+			}
+		}
+
+		rec, err := it.read()
+		if errors.Is(err, io.EOF) {
+			it.err = it.advance()
+			continue
+		}
+
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if rec.LSN >= it.from {
+			it.rec = rec
+			return true
+		}
+	}
+
+	return false
+}
+
+// Record returns record which iterator points to after Next has returned true
+func (it *iterator) Record() Record {
+	return it.rec
+}
+
+// Err returns error which has stopped iterator, nil means all records have been read
+func (it *iterator) Err() error {
+	return it.err
+}
+
+// Close gives back segment which iterator is reading to fspool
+func (it *iterator) Close() error {
+	it.closed = true
+
+	return it.release()
+}
+
+// open gets segment of iterator from fspool and finds how far it can be read, segment can't be truncated meanwhile
+func (it *iterator) open() error {
+	it.w.mu.Lock()
+	defer it.w.mu.Unlock()
+
+	seg, err := it.w.hold(it.base)
+	if err != nil {
+		return err
+	}
+
+	it.seg = seg
+
+	return it.locate(it.w.bases, it.w.segSize)
+}
+
+// refresh finds whether segment of iterator is still current segment of wal and how far it can be read
+func (it *iterator) refresh() error {
+	return it.locate(it.w.segments())
+}
+
+// locate finds segment of iterator between segments of wal and how far it can be read, size is size of records of current segment
+func (it *iterator) locate(bases []uint64, size int64) error {
+	i := segmentOf(bases, it.base)
+	if i < 0 || bases[i] != it.base {
+		return ErrWALLSNIsTruncated
+	}
+
+	if i == len(bases)-1 {
+		it.nextBase = 0
+		it.limit = size
+		return nil
+	}
+
+	it.nextBase = bases[i+1]
+	it.limit = -1
+
+	return nil
+}
+
+// advance moves iterator to the next segment once its segment has been read until its end
+func (it *iterator) advance() error {
+	path := segmentPath(it.w.config.Dir, it.base)
+
+	// records of segment should continue in the next segment
+	if it.lsn != it.nextBase {
+		return errs.New("read", path, it.offset, ErrWALCorruptedRecord, io.ErrUnexpectedEOF)
+	}
+
+	if err := it.release(); err != nil {
+		return err
+	}
+
+	it.base = it.nextBase
+	it.offset = 0
+
+	return nil
+}
+
+// read reads the next record of segment, it returns io.EOF once segment has been read until its end
+func (it *iterator) read() (Record, error) {
+	path := segmentPath(it.w.config.Dir, it.base)
+
+	header, err := it.seg.ReadDataContext(context.Background(), it.offset, headerSize, io.SeekStart)
+	if errors.Is(err, io.EOF) && it.limit < 0 {
+		return Record{}, io.EOF
+	}
+
+	if err := readErr(path, it.offset, err); err != nil {
+		return Record{}, err
+	}
+
+	length, sum := decodeHeader(header)
+	data := []byte{}
+
+	if length > 0 {
+		data, err = it.seg.ReadDataContext(context.Background(), it.offset+headerSize, int(length), io.SeekStart)
+		if err := readErr(path, it.offset, err); err != nil {
+			return Record{}, err
+		}
+	}
+
+	if checksum(it.lsn, data) != sum {
+		return Record{}, errs.New("read", path, it.offset, ErrWALCorruptedRecord, nil)
+	}
+
+	rec := Record{LSN: it.lsn, Data: data}
+	it.lsn++
+	it.offset += headerSize + int64(length)
+
+	return rec, nil
+}
+
+// release gives back segment of iterator to fspool
+func (it *iterator) release() error {
+	if it.seg == nil {
+		return nil
+	}
+
+	seg := it.seg
+	it.seg = nil
+
+	return it.w.unhold(it.base, seg)
+}
+
+// readErr converts error of reading record at offset into error of wal, end of segment in the middle of record means it's torn
+func readErr(path string, offset int64, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errs.New("read", path, offset, ErrWALCorruptedRecord, err)
+	}
+
+	if err != nil {
+		return errs.New("read", path, offset, ErrWALCouldNotRead, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package wal
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// replay returns LSNs and data of records which are read by iterator from lsn
+func replay(t *testing.T, w WAL, lsn uint64) ([]uint64, []string, error) {
+	it, err := w.Replay(lsn)
+	assert.Nil(t, err)
+	defer it.Close()
+
+	var lsns []uint64
+	var data []string
+
+	for it.Next() {
+		lsns = append(lsns, it.Record().LSN)
+		data = append(data, string(it.Record().Data))
+	}
+
+	return lsns, data, it.Err()
+}
+
+func TestIterator(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	appendRecords(t, w, 7)
+
+	lsns, data, err := replay(t, w, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7}, lsns)
+	for i, d := range data {
+		assert.Equal(t, fmt.Sprintf("record-%02d", i), d)
+	}
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_FromLSN(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	appendRecords(t, w, 7)
+
+	lsns, data, err := replay(t, w, 5)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{5, 6, 7}, lsns)
+	assert.Equal(t, "record-04", data[0])
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_NotAppendedYet(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	appendRecords(t, w, 2)
+
+	it, _ := w.Replay(4)
+
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+
+	// records which are appended meanwhile are read by the next call of Next, even across segments
+	appendRecords(t, w, 5)
+
+	var lsns []uint64
+	for it.Next() {
+		lsns = append(lsns, it.Record().LSN)
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []uint64{4, 5, 6, 7}, lsns)
+	assert.Nil(t, it.Close())
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_EmptyRecord(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+
+	_, _ = w.Append(nil)
+	appendRecords(t, w, 1)
+
+	lsns, data, err := replay(t, w, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2}, lsns)
+	assert.Equal(t, []string{"", "record-00"}, data)
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_CorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 5)
+	assert.Nil(t, w.Close())
+
+	// a byte of the second record of the first segment is flipped
+	path := segmentPath(dir, 1)
+	rawData, _ := os.ReadFile(path)
+	rawData[2*headerSize+9+1] ^= 0xff
+	assert.Nil(t, os.WriteFile(path, rawData, 0644))
+
+	w, _ = NewWAL(newTestConfig(dir), newTestPool(t))
+
+	lsns, _, err := replay(t, w, 1)
+
+	assert.ErrorIs(t, err, ErrWALCorruptedRecord)
+	assert.Equal(t, []uint64{1}, lsns)
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_MissingRecords(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 5)
+	assert.Nil(t, w.Close())
+
+	// the last record of the first segment is lost
+	path := segmentPath(dir, 1)
+	rawData, _ := os.ReadFile(path)
+	assert.Nil(t, os.WriteFile(path, rawData[:2*(headerSize+9)], 0644))
+
+	w, _ = NewWAL(newTestConfig(dir), newTestPool(t))
+
+	lsns, _, err := replay(t, w, 1)
+
+	assert.ErrorIs(t, err, ErrWALCorruptedRecord)
+	assert.Equal(t, []uint64{1, 2}, lsns)
+	assert.Nil(t, w.Close())
+}
+
+func TestIterator_Close(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	appendRecords(t, w, 2)
+
+	it, _ := w.Replay(1)
+	assert.True(t, it.Next())
+	assert.Nil(t, it.Close())
+
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+	assert.Nil(t, w.Close())
+}
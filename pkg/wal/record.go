@@ -0,0 +1,65 @@
+package wal
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// headerSize is size of header of each record, it's length of data followed by its checksum
+const headerSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Record is an entry of wal with its log sequence number
+type Record struct {
+	LSN  uint64
+	Data []byte
+}
+
+// encodeRecord returns length-prefixed data of record with its checksum
+func encodeRecord(lsn uint64, data []byte) []byte {
+	rec := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], checksum(lsn, data))
+	copy(rec[headerSize:], data)
+
+	return rec
+}
+
+// decodeHeader returns length of data and checksum of record from its header
+func decodeHeader(header []byte) (uint32, uint32) {
+	return binary.BigEndian.Uint32(header[0:4]), binary.BigEndian.Uint32(header[4:8])
+}
+
+// checksum returns crc32 (castagnoli) of lsn followed by data, so zeroed or misplaced records don't pass as valid ones
+func checksum(lsn uint64, data []byte) uint32 {
+	var lsnBytes [8]byte
+	binary.BigEndian.PutUint64(lsnBytes[:], lsn)
+
+	return crc32.Update(crc32.Checksum(lsnBytes[:], crcTable), crcTable, data)
+}
+
+// scanRecords walks valid records of segment whose first record is baseLSN, it returns number of them and offset which they end at
+// scanning stops at the first torn or corrupted record, so data after it is not part of wal
+func scanRecords(rawData []byte, baseLSN uint64) (uint64, int64) {
+	var count uint64
+	var end int64
+
+	for int64(len(rawData))-end >= headerSize {
+		length, sum := decodeHeader(rawData[end : end+headerSize])
+
+		dataEnd := end + headerSize + int64(length)
+		if dataEnd > int64(len(rawData)) {
+			break
+		}
+
+		if checksum(baseLSN+count, rawData[end+headerSize:dataEnd]) != sum {
+			break
+		}
+
+		count++
+		end = dataEnd
+	}
+
+	return count, end
+}
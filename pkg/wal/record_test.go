@@ -0,0 +1,67 @@
+package wal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncodeRecord(t *testing.T) {
+	rec := encodeRecord(1, []byte("data"))
+
+	length, sum := decodeHeader(rec)
+
+	assert.Len(t, rec, headerSize+4)
+	assert.Equal(t, uint32(4), length)
+	assert.Equal(t, checksum(1, []byte("data")), sum)
+	assert.Equal(t, []byte("data"), rec[headerSize:])
+}
+
+func TestChecksum_DependsOnLSN(t *testing.T) {
+	assert.NotEqual(t, checksum(1, []byte("data")), checksum(2, []byte("data")))
+	assert.NotEqual(t, uint32(0), checksum(1, nil))
+}
+
+func TestScanRecords(t *testing.T) {
+	var rawData []byte
+	rawData = append(rawData, encodeRecord(5, []byte("a"))...)
+	rawData = append(rawData, encodeRecord(6, nil)...)
+	rawData = append(rawData, encodeRecord(7, []byte("bc"))...)
+
+	count, end := scanRecords(rawData, 5)
+
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, int64(len(rawData)), end)
+}
+
+func TestScanRecords_TornTail(t *testing.T) {
+	rawData := encodeRecord(1, []byte("a"))
+	valid := int64(len(rawData))
+	rawData = append(rawData, encodeRecord(2, []byte("bc"))[:headerSize+1]...)
+
+	count, end := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, valid, end)
+}
+
+func TestScanRecords_ZeroedTail(t *testing.T) {
+	rawData := encodeRecord(1, []byte("a"))
+	valid := int64(len(rawData))
+	rawData = append(rawData, make([]byte, 2*headerSize)...)
+
+	count, end := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, valid, end)
+}
+
+func TestScanRecords_CorruptedRecord(t *testing.T) {
+	rawData := encodeRecord(1, []byte("a"))
+	rawData = append(rawData, encodeRecord(2, []byte("b"))...)
+	rawData[len(rawData)-1] = 'x'
+
+	count, end := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, int64(headerSize+1), end)
+}
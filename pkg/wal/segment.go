@@ -0,0 +1,51 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segmentExt is extension of segment files, name of each segment is LSN of its first record
+const segmentExt = ".wal"
+
+// segmentPath returns path of segment whose first record is baseLSN
+func segmentPath(dir string, baseLSN uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", baseLSN, segmentExt))
+}
+
+// listSegments returns LSN of first records of segments in dir in ascending order, other files are skipped
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []uint64
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		bases = append(bases, base)
+	}
+
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+
+	return bases, nil
+}
+
+// segmentOf returns index of segment which contains lsn, bases should be in ascending order and start before lsn
+func segmentOf(bases []uint64, lsn uint64) int {
+	return sort.Search(len(bases), func(i int) bool { return bases[i] > lsn }) - 1
+}
@@ -0,0 +1,42 @@
+package wal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSegmentPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("/wal", "00000000000000000042.wal"), segmentPath("/wal", 42))
+}
+
+func TestListSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"00000000000000000011.wal", "00000000000000000001.wal", "notes.txt", "x.wal", ".00000000000000000001.wal.replace-1"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "00000000000000000021.wal"), os.ModePerm))
+
+	bases, err := listSegments(dir)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{1, 11}, bases)
+}
+
+func TestListSegments_DirIsNotExists(t *testing.T) {
+	_, err := listSegments(filepath.Join(t.TempDir(), "wal"))
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSegmentOf(t *testing.T) {
+	bases := []uint64{1, 11, 21}
+
+	assert.Equal(t, -1, segmentOf(bases, 0))
+	assert.Equal(t, 0, segmentOf(bases, 1))
+	assert.Equal(t, 0, segmentOf(bases, 10))
+	assert.Equal(t, 1, segmentOf(bases, 11))
+	assert.Equal(t, 2, segmentOf(bases, 100))
+}
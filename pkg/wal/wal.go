@@ -0,0 +1,588 @@
+/*
+Package wal is a write-ahead log on top of fspool, written for services which
+should replay their changes after a crash.
+
+Records are length-prefixed & checksummed and they are appended to segment files
+which are managed by fspool, each record is identified by its log sequence number (LSN).
+*/
+package wal
+
+import (
+	"context"
+	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	walConfig "github.com/amirvalhalla/fspool/pkg/cfgs/wal"
+	"github.com/amirvalhalla/fspool/pkg/errs"
+	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/fs"
+	"github.com/amirvalhalla/fspool/pkg/fspool"
+	"github.com/amirvalhalla/fspool/pkg/logger"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWALDirIsEmpty          = errors.New("package wal - directory of wal is empty")
+	ErrWALSegmentSizeIsZero   = errors.New("package wal - segment size should be greater than zero")
+	ErrWALSyncDurationIsZero  = errors.New("package wal - sync duration should be greater than zero")
+	ErrWALCouldNotOpen        = errors.New("package wal - could not open wal")
+	ErrWALCouldNotOpenSegment = errors.New("package wal - could not open segment")
+	ErrWALRecordTooLarge      = errors.New("package wal - record is larger than 4 GB")
+	ErrWALCouldNotAppend      = errors.New("package wal - could not append record")
+	ErrWALCouldNotSync        = errors.New("package wal - could not sync records")
+	ErrWALCouldNotRead        = errors.New("package wal - could not read record")
+	ErrWALCorruptedRecord     = errors.New("package wal - record is torn or its checksum doesn't match")
+	ErrWALLSNIsTruncated      = errors.New("package wal - lsn has been truncated")
+	ErrWALCouldNotTruncate    = errors.New("package wal - could not truncate segments")
+	ErrWALClosed              = errors.New("package wal - wal is closed")
+)
+
+type WAL interface {
+	// Append writes data as a new record and returns its LSN, record is durable once it's synced by sync policy or Sync
+	Append(data []byte) (uint64, error)
+	// Sync syncs appended records into disk, concurrent callers share fsyncs
+	Sync() error
+	// Replay returns iterator over records from lsn, it returns ErrWALLSNIsTruncated if lsn has been truncated
+	Replay(lsn uint64) (Iterator, error)
+	// Truncate removes segments whose all records are before lsn, current segment and segments which are being read by iterators are never removed
+	Truncate(lsn uint64) error
+	// FirstLSN returns LSN of the oldest record which is kept by wal
+	FirstLSN() uint64
+	// NextLSN returns LSN which the next appended record gets
+	NextLSN() uint64
+	// Close syncs appended records and gives back current segment to fspool, fspool itself is not closed
+	Close() error
+}
+
+type wal struct {
+	config       walConfig.WALConfiguration
+	pool         fspool.FSPool
+	log          logger.Logger
+	mu           sync.Mutex
+	segMu        sync.RWMutex  // held for reading while current segment is synced out of mu, so it's not given back to fspool meanwhile
+	bases        []uint64      // LSN of first records of segments in ascending order, the last one is current segment
+	seg          fs.Filesystem // current segment which records are appended to
+	segSize      int64         // size of records of current segment, records beyond it are still being written
+	nextLSN      uint64
+	unsynced     uint64         // size of records which have been appended since the last sync
+	err          error          // failure of append or sync, records may have been lost so wal refuses appending until it's reopened
+	heldMu       sync.Mutex     // guards held, so iterators give back their segments without waiting for w.mu which may be held by a waiting rollover
+	held         map[uint64]int // number of iterators which are reading segments by LSN of their first records, they're not truncated meanwhile
+	closed       bool
+	stop         chan struct{} // closed by Close, so rollover gives up waiting for a place of fspool
+	stopOnce     sync.Once
+	syncerStop   chan struct{}
+	syncerOnce   sync.Once
+	syncerWg     sync.WaitGroup
+	openFileFunc fs.OpenFile
+}
+
+// NewWAL opens write-ahead log of config.Dir whose segments are handed out by pool, pool should have RW permission
+// torn or corrupted records at the end of the last segment are dropped, because their Append has not been completed
+func NewWAL(config walConfig.WALConfiguration, pool fspool.FSPool) (WAL, error) {
+	if config.Dir == "" {
+		return nil, ErrWALDirIsEmpty
+	}
+
+	if config.SegmentSize == 0 {
+		return nil, ErrWALSegmentSizeIsZero
+	}
+
+	if config.SyncType == cfgs.FlushByTime && config.SyncDuration <= 0 {
+		return nil, ErrWALSyncDurationIsZero
+	}
+
+	if err := os.MkdirAll(config.Dir, os.ModePerm); err != nil {
+		return nil, errs.New("mkdir", config.Dir, errs.NoOffset, ErrWALCouldNotOpen, err)
+	}
+
+	bases, err := listSegments(config.Dir)
+	if err != nil {
+		return nil, errs.New("open", config.Dir, errs.NoOffset, ErrWALCouldNotOpen, err)
+	}
+
+	w := &wal{
+		config:       config,
+		pool:         pool,
+		log:          logger.OrNop(config.Logger),
+		bases:        bases,
+		held:         make(map[uint64]int),
+		stop:         make(chan struct{}),
+		openFileFunc: openOSFile,
+	}
+
+	if len(w.bases) == 0 {
+		w.bases = []uint64{1}
+	}
+
+	if err := w.recover(len(bases) == 0); err != nil {
+		return nil, err
+	}
+
+	if config.SyncType == cfgs.FlushByTime {
+		w.startSyncer()
+	}
+
+	return w, nil
+}
+
+// Append writes data as a new record at the end of current segment and returns its LSN
+// a new segment is started if record doesn't fit in current one, record is synced before returning if sync policy requires it
+func (w *wal) Append(data []byte) (uint64, error) {
+	if uint64(len(data)) > math.MaxUint32 {
+		return 0, ErrWALRecordTooLarge
+	}
+
+	w.mu.Lock()
+
+	if err := w.validate(); err != nil {
+		w.mu.Unlock()
+		return 0, err
+	}
+
+	rec := encodeRecord(w.nextLSN, data)
+
+	// a record which is larger than segment size gets its own segment
+	if w.segSize > 0 && uint64(w.segSize)+uint64(len(rec)) > w.config.SegmentSize {
+		if err := w.roll(); err != nil {
+			w.err = err
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+
+	if err := w.seg.Write(rec, w.segSize, io.SeekStart); err != nil {
+		w.err = errs.New("append", w.segmentPath(), w.segSize, ErrWALCouldNotAppend, err)
+		w.mu.Unlock()
+		return 0, w.fail(w.err)
+	}
+
+	lsn := w.nextLSN
+	w.nextLSN++
+	w.segSize += int64(len(rec))
+	w.unsynced += uint64(len(rec))
+
+	if w.config.SyncType != cfgs.FlushBySize || w.unsynced < w.config.SyncSize {
+		w.mu.Unlock()
+		return lsn, nil
+	}
+
+	if err := w.commit(); err != nil {
+		return 0, err
+	}
+
+	return lsn, nil
+}
+
+// Sync syncs appended records into disk, concurrent callers share fsyncs
+func (w *wal) Sync() error {
+	w.mu.Lock()
+
+	if err := w.validate(); err != nil {
+		w.mu.Unlock()
+		return err
+	}
+
+	return w.commit()
+}
+
+// Replay returns iterator over records from lsn, it returns ErrWALLSNIsTruncated if lsn has been truncated
+// records which are appended while iterating are read by iterator as well, so lsn may not be appended yet
+// iterator gets segments which are not current segment from fspool, so limit of fspool should leave a place for each iterator
+func (w *wal) Replay(lsn uint64) (Iterator, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, ErrWALClosed
+	}
+
+	if lsn < w.bases[0] {
+		return nil, ErrWALLSNIsTruncated
+	}
+
+	base := w.bases[segmentOf(w.bases, lsn)]
+
+	return &iterator{
+		w:     w,
+		base:  base,
+		lsn:   base,
+		from:  lsn,
+		limit: -1,
+	}, nil
+}
+
+// Truncate removes segments whose all records are before lsn, current segment is never removed
+// segments are removed from the oldest one, so a crash in the middle of truncating doesn't leave any gap
+// truncating stops at the oldest segment which is being read by an iterator, it's removed by a later Truncate once it's released
+func (w *wal) Truncate(lsn uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+
+	removed := 0
+
+	for len(w.bases) > 1 && w.bases[1] <= lsn && !w.isHeld(w.bases[0]) {
+		path := segmentPath(w.config.Dir, w.bases[0])
+
+		// segment may be kept idle by eviction policy of fspool
+		if err := w.pool.Remove(path); err != nil && !errors.Is(err, fspool.ErrFSPoolFilesystemIsNotExists) {
+			return w.fail(errs.New("truncate", path, errs.NoOffset, ErrWALCouldNotTruncate, err))
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return w.fail(errs.New("truncate", path, errs.NoOffset, ErrWALCouldNotTruncate, err))
+		}
+
+		w.bases = w.bases[1:]
+		removed++
+	}
+
+	if removed == 0 {
+		return nil
+	}
+
+	// removed segments don't come back after a crash once directory is synced
+	if err := fs.SyncDirectory(w.config.Dir, w.openFileFunc); err != nil {
+		return w.fail(errs.New("sync", w.config.Dir, errs.NoOffset, ErrWALCouldNotTruncate, err))
+	}
+
+	w.log.Info("package wal - truncated segments", "dir", w.config.Dir, "segments", removed, "first lsn", w.bases[0])
+
+	return nil
+}
+
+// FirstLSN returns LSN of the oldest record which is kept by wal
+func (w *wal) FirstLSN() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.bases[0]
+}
+
+// NextLSN returns LSN which the next appended record gets
+func (w *wal) NextLSN() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.nextLSN
+}
+
+// Close syncs appended records and gives back current segment to fspool, fspool itself is not closed
+func (w *wal) Close() error {
+	w.stopSyncer()
+
+	// rollover which is waiting for a place of fspool holds w.mu, so it's stopped before locking
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWALClosed
+	}
+
+	w.closed = true
+
+	// waiting for syncs which are running out of w.mu
+	w.segMu.Lock()
+	defer w.segMu.Unlock()
+
+	// rollover has failed after giving back the previous segment
+	if w.seg == nil {
+		return w.err
+	}
+
+	var syncErr error
+	if err := w.seg.Sync(); err != nil {
+		syncErr = w.fail(errs.New("sync", w.segmentPath(), errs.NoOffset, ErrWALCouldNotSync, err))
+	}
+
+	return errs.Join(syncErr, w.pool.Release(w.seg))
+}
+
+// recover opens the last segment as current segment and finds the next LSN by its valid records, torn tail of segment is dropped
+// directory is synced if segment is created, so it survives a crash (caller must hold w.mu)
+func (w *wal) recover(created bool) error {
+	base := w.bases[len(w.bases)-1]
+	path := segmentPath(w.config.Dir, base)
+
+	seg, err := w.pool.Get(path)
+	if err != nil {
+		return errs.New("open", path, errs.NoOffset, ErrWALCouldNotOpenSegment, err)
+	}
+
+	rawData, err := seg.ReadAllData()
+	if err != nil {
+		_ = w.pool.Release(seg)
+		return errs.New("read", path, errs.NoOffset, ErrWALCouldNotOpenSegment, err)
+	}
+
+	count, end := scanRecords(rawData, base)
+
+	// last Append before crash has not been completed, so its record is replaced atomically by valid ones
+	if end < int64(len(rawData)) {
+		w.log.Warn("package wal - dropped torn tail of segment", "path", path, "offset", end, "bytes", int64(len(rawData))-end)
+
+		if err := seg.ReplaceAll(rawData[:end]); err != nil {
+			_ = w.pool.Release(seg)
+			return errs.New("recover", path, end, ErrWALCouldNotOpenSegment, err)
+		}
+	}
+
+	if created {
+		if err := fs.SyncDirectory(w.config.Dir, w.openFileFunc); err != nil {
+			_ = w.pool.Release(seg)
+			return errs.New("sync", w.config.Dir, errs.NoOffset, ErrWALCouldNotOpenSegment, err)
+		}
+	}
+
+	w.seg = seg
+	w.segSize = end
+	w.nextLSN = base + count
+
+	return nil
+}
+
+// roll syncs current segment and starts a new segment from the next LSN, then it gives back current segment to fspool (caller must hold w.mu)
+func (w *wal) roll() error {
+	// waiting for syncs which are running out of w.mu
+	w.segMu.Lock()
+	defer w.segMu.Unlock()
+
+	if err := w.seg.Sync(); err != nil {
+		return w.fail(errs.New("sync", w.segmentPath(), errs.NoOffset, ErrWALCouldNotSync, err))
+	}
+
+	w.unsynced = 0
+
+	path := segmentPath(w.config.Dir, w.nextLSN)
+
+	// the next segment is got before giving back current one, so wal keeps its segment if the next one can't be opened
+	seg, err := w.pool.Get(path)
+	if errors.Is(err, fspool.ErrFSPoolLimitReached) {
+		seg, err = w.swap(path)
+	}
+	if err != nil {
+		return w.fail(errs.New("open", path, errs.NoOffset, ErrWALCouldNotOpenSegment, err))
+	}
+
+	// the next segment is open already, so failure of giving back current one is only reported
+	if w.seg != nil {
+		if err := w.pool.Release(w.seg); err != nil {
+			w.log.Error(err.Error(), "path", w.segmentPath())
+		}
+	}
+
+	w.seg = seg
+	w.segSize = 0
+	w.bases = append(w.bases, w.nextLSN)
+
+	if err := fs.SyncDirectory(w.config.Dir, w.openFileFunc); err != nil {
+		return w.fail(errs.New("sync", w.config.Dir, errs.NoOffset, ErrWALCouldNotSync, err))
+	}
+
+	w.log.Info("package wal - rolled over segment", "path", path, "lsn", w.nextLSN)
+
+	return nil
+}
+
+// swap gives back current segment to fspool and waits for a place of the next segment when fspool has reached its limit
+// wal holds only one place of fspool then, so it gets the place of current segment once no one else is waiting for it (caller must hold w.mu & w.segMu)
+// waiting is given up after config.RolloverTimeout or once wal is closed, because appending & syncing wait for w.mu meanwhile
+func (w *wal) swap(path string) (fs.Filesystem, error) {
+	if err := w.pool.Release(w.seg); err != nil {
+		return nil, err
+	}
+
+	w.seg = nil
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if w.config.RolloverTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), w.config.RolloverTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return w.pool.Acquire(ctx, path)
+}
+
+// commit syncs current segment out of w.mu, so concurrent callers share fsyncs by group commit of fs (caller must hold w.mu, it's unlocked)
+func (w *wal) commit() error {
+	seg := w.seg
+	path := w.segmentPath()
+	w.unsynced = 0
+
+	w.segMu.RLock()
+	w.mu.Unlock()
+
+	err := seg.Sync()
+	w.segMu.RUnlock()
+
+	if err == nil {
+		return nil
+	}
+
+	err = w.fail(errs.New("sync", path, errs.NoOffset, ErrWALCouldNotSync, err))
+
+	// data which has failed to be synced may be dropped by kernel, so appending is refused
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+
+	return err
+}
+
+// startSyncer syncs appended records by timer every config.SyncDuration
+func (w *wal) startSyncer() {
+	w.syncerStop = make(chan struct{})
+	w.syncerWg.Add(1)
+
+	go func() {
+		defer w.syncerWg.Done()
+
+		ticker := time.NewTicker(w.config.SyncDuration)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.syncerStop:
+				return
+			case <-ticker.C:
+				w.syncByTime()
+			}
+		}
+	}()
+}
+
+// stopSyncer stops goroutine of startSyncer and waits for its running sync
+func (w *wal) stopSyncer() {
+	if w.syncerStop == nil {
+		return
+	}
+
+	w.syncerOnce.Do(func() {
+		close(w.syncerStop)
+	})
+
+	w.syncerWg.Wait()
+}
+
+// syncByTime syncs appended records if there is any and reports its error to config.SyncErrorHandler
+func (w *wal) syncByTime() {
+	w.mu.Lock()
+
+	if w.closed || w.err != nil || w.unsynced == 0 {
+		w.mu.Unlock()
+		return
+	}
+
+	// there isn't any caller to get the error, so it's reported to sync error handler (commit has reported it to logger)
+	if err := w.commit(); err != nil && w.config.SyncErrorHandler != nil {
+		w.config.SyncErrorHandler(err)
+	}
+}
+
+// segments returns LSN of first records of segments and size of records of current segment
+func (w *wal) segments() ([]uint64, int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	bases := make([]uint64, len(w.bases))
+	copy(bases, w.bases)
+
+	return bases, w.segSize
+}
+
+// hold gets segment of base from fspool on behalf of an iterator, it's not truncated until it's given back by unhold (caller must hold w.mu)
+// segment is checked before getting it, so a truncated segment is not created again by fspool
+func (w *wal) hold(base uint64) (fs.Filesystem, error) {
+	if i := segmentOf(w.bases, base); i < 0 || w.bases[i] != base {
+		return nil, ErrWALLSNIsTruncated
+	}
+
+	path := segmentPath(w.config.Dir, base)
+
+	seg, err := w.pool.Get(path)
+	if err != nil {
+		return nil, errs.New("open", path, errs.NoOffset, ErrWALCouldNotOpenSegment, err)
+	}
+
+	w.heldMu.Lock()
+	w.held[base]++
+	w.heldMu.Unlock()
+
+	return seg, nil
+}
+
+// unhold gives back segment of base which has been got by hold to fspool, segment can be truncated once it's not held anymore
+func (w *wal) unhold(base uint64, seg fs.Filesystem) error {
+	err := w.pool.Release(seg)
+
+	w.heldMu.Lock()
+	if w.held[base]--; w.held[base] == 0 {
+		delete(w.held, base)
+	}
+	w.heldMu.Unlock()
+
+	return err
+}
+
+// isHeld reports whether segment of base is being read by an iterator (caller must hold w.mu, so segments are not held meanwhile)
+func (w *wal) isHeld(base uint64) bool {
+	w.heldMu.Lock()
+	defer w.heldMu.Unlock()
+
+	return w.held[base] > 0
+}
+
+// segmentPath returns path of current segment (caller must hold w.mu)
+func (w *wal) segmentPath() string {
+	return segmentPath(w.config.Dir, w.bases[len(w.bases)-1])
+}
+
+// validate reports whether records can be appended or synced (caller must hold w.mu)
+func (w *wal) validate() error {
+	if w.closed {
+		return ErrWALClosed
+	}
+
+	return w.err
+}
+
+// fail reports err to logger of wal and returns it
+func (w *wal) fail(err error) error {
+	w.log.Error(err.Error(), "dir", w.config.Dir)
+	return err
+}
+
+// openOSFile opens file by os package
+func openOSFile(name string, flag int, perm os.FileMode) (file.File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,469 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	fspoolConfig "github.com/amirvalhalla/fspool/pkg/cfgs/fspool"
+	walConfig "github.com/amirvalhalla/fspool/pkg/cfgs/wal"
+	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/fspool"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPool(t *testing.T) fspool.FSPool {
+	pool, err := fspool.NewFSPool(fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       4,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = pool.Close(context.Background()) })
+
+	return pool
+}
+
+// newTestConfig returns configuration whose segments keep 3 records of appendRecords
+func newTestConfig(dir string) walConfig.WALConfiguration {
+	config := walConfig.WALConfiguration{}
+	config.New()
+	config.Dir = dir
+	config.SegmentSize = 3 * (headerSize + 9)
+
+	return config
+}
+
+// appendRecords appends n records of 9 bytes and returns their LSNs
+func appendRecords(t *testing.T, w WAL, n int) []uint64 {
+	var lsns []uint64
+
+	for i := 0; i < n; i++ {
+		lsn, err := w.Append([]byte(fmt.Sprintf("record-%02d", i)))
+		assert.Nil(t, err)
+		lsns = append(lsns, lsn)
+	}
+
+	return lsns
+}
+
+func TestNewWAL(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "wal")
+
+	w, err := NewWAL(newTestConfig(dir), newTestPool(t))
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), w.FirstLSN())
+	assert.Equal(t, uint64(1), w.NextLSN())
+	assert.FileExists(t, segmentPath(dir, 1))
+	assert.Nil(t, w.Close())
+}
+
+func TestNewWAL_DirIsEmpty(t *testing.T) {
+	_, err := NewWAL(newTestConfig(""), newTestPool(t))
+
+	assert.ErrorIs(t, err, ErrWALDirIsEmpty)
+}
+
+func TestNewWAL_SegmentSizeIsZero(t *testing.T) {
+	config := newTestConfig(t.TempDir())
+	config.SegmentSize = 0
+
+	_, err := NewWAL(config, newTestPool(t))
+
+	assert.ErrorIs(t, err, ErrWALSegmentSizeIsZero)
+}
+
+func TestNewWAL_SyncDurationIsZero(t *testing.T) {
+	config := newTestConfig(t.TempDir())
+	config.SyncType = cfgs.FlushByTime
+
+	_, err := NewWAL(config, newTestPool(t))
+
+	assert.ErrorIs(t, err, ErrWALSyncDurationIsZero)
+}
+
+func TestNewWAL_CouldNotOpenSegment(t *testing.T) {
+	pool := newTestPool(t)
+	assert.Nil(t, pool.Close(context.Background()))
+
+	_, err := NewWAL(newTestConfig(t.TempDir()), pool)
+
+	assert.ErrorIs(t, err, ErrWALCouldNotOpenSegment)
+	assert.ErrorIs(t, err, fspool.ErrFSPoolClosed)
+}
+
+func TestWAL_Append(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+
+	lsns := appendRecords(t, w, 2)
+
+	assert.Equal(t, []uint64{1, 2}, lsns)
+	assert.Equal(t, uint64(3), w.NextLSN())
+
+	// each Append is synced by default config, so records are in file before closing
+	rawData, _ := os.ReadFile(segmentPath(dir, 1))
+	count, end := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(2), count)
+	assert.Equal(t, int64(len(rawData)), end)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Append_RollsOverSegment(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+
+	appendRecords(t, w, 7)
+
+	bases, _ := listSegments(dir)
+
+	assert.Equal(t, []uint64{1, 4, 7}, bases)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Append_RollsOverSegment_PoolIsFull(t *testing.T) {
+	dir := t.TempDir()
+	pool, _ := fspool.NewFSPool(fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       1,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	})
+	t.Cleanup(func() { _ = pool.Close(context.Background()) })
+
+	w, _ := NewWAL(newTestConfig(dir), pool)
+
+	// current segment holds the only place of fspool, so it's given back before waiting for the next one
+	appendRecords(t, w, 7)
+
+	bases, _ := listSegments(dir)
+
+	assert.Equal(t, []uint64{1, 4, 7}, bases)
+	assert.Equal(t, 1, pool.Len())
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Append_RollsOverSegment_PoolIsFull_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	pool, _ := fspool.NewFSPool(fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       1,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	})
+	t.Cleanup(func() { _ = pool.Close(context.Background()) })
+
+	config := newTestConfig(dir)
+	config.RolloverTimeout = 20 * time.Millisecond
+	w, _ := NewWAL(config, pool)
+	appendRecords(t, w, 3)
+
+	// iterator shares current segment, so it keeps the only place of fspool after wal gives the segment back
+	it, _ := w.Replay(1)
+	assert.True(t, it.Next())
+
+	_, err := w.Append([]byte("record-03"))
+
+	assert.ErrorIs(t, err, ErrWALCouldNotOpenSegment)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, it.Close())
+	assert.ErrorIs(t, w.Close(), context.DeadlineExceeded)
+}
+
+func TestWAL_Append_RollsOverSegment_PoolIsFull_Close(t *testing.T) {
+	dir := t.TempDir()
+	pool, _ := fspool.NewFSPool(fspoolConfig.FSPoolConfiguration{
+		Perm:        cfgs.RW,
+		MemoryRent:  1024,
+		Limit:       1,
+		ReaderLimit: 1,
+		FlushType:   cfgs.FlushBySize,
+		FlushSize:   512,
+	})
+	t.Cleanup(func() { _ = pool.Close(context.Background()) })
+
+	w, _ := NewWAL(newTestConfig(dir), pool)
+	appendRecords(t, w, 3)
+
+	it, _ := w.Replay(1)
+	assert.True(t, it.Next())
+
+	appended := make(chan error)
+	go func() {
+		_, err := w.Append([]byte("record-03"))
+		appended <- err
+	}()
+
+	assert.Eventually(t, func() bool { return pool.Stats().Waiters == 1 }, time.Second, time.Millisecond)
+
+	// Close stops rollover which is waiting for a place of fspool
+	assert.ErrorIs(t, w.Close(), context.Canceled)
+	assert.ErrorIs(t, <-appended, context.Canceled)
+	assert.Nil(t, it.Close())
+}
+
+func TestWAL_Append_CouldNotOpenNextSegment(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 3)
+
+	// next segment can't be opened as a file
+	assert.Nil(t, os.Mkdir(segmentPath(dir, 4), os.ModePerm))
+
+	_, err := w.Append([]byte("record-03"))
+
+	assert.ErrorIs(t, err, ErrWALCouldNotOpenSegment)
+
+	// current segment is kept, so it's synced and given back by Close
+	assert.Nil(t, w.Close())
+
+	rawData, _ := os.ReadFile(segmentPath(dir, 1))
+	count, _ := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(3), count)
+}
+
+func TestWAL_Append_LargerThanSegment(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+
+	appendRecords(t, w, 1)
+	lsn, err := w.Append(make([]byte, 100))
+	assert.Nil(t, err)
+	appendRecords(t, w, 1)
+
+	bases, _ := listSegments(dir)
+
+	assert.Equal(t, uint64(2), lsn)
+	assert.Equal(t, []uint64{1, 2, 3}, bases)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Append_Concurrent(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+
+	lsns := make([]uint64, 50)
+	var wg sync.WaitGroup
+
+	for i := range lsns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lsn, err := w.Append([]byte(fmt.Sprintf("record-%02d", i)))
+			assert.Nil(t, err)
+			lsns[i] = lsn
+		}(i)
+	}
+
+	wg.Wait()
+
+	// each record has a unique LSN and it's replayed with its own data
+	it, _ := w.Replay(1)
+	seen := make(map[uint64]bool)
+
+	for it.Next() {
+		rec := it.Record()
+		seen[rec.LSN] = true
+		for i, lsn := range lsns {
+			if lsn == rec.LSN {
+				assert.Equal(t, fmt.Sprintf("record-%02d", i), string(rec.Data))
+			}
+		}
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Len(t, seen, len(lsns))
+	assert.Nil(t, it.Close())
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Append_Closed(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	assert.Nil(t, w.Close())
+
+	_, err := w.Append([]byte("data"))
+
+	assert.ErrorIs(t, err, ErrWALClosed)
+	assert.ErrorIs(t, w.Sync(), ErrWALClosed)
+	assert.ErrorIs(t, w.Close(), ErrWALClosed)
+}
+
+func TestWAL_Sync_BySize(t *testing.T) {
+	config := newTestConfig(t.TempDir())
+	config.SyncSize = 2 * (headerSize + 9)
+	w, _ := NewWAL(config, newTestPool(t))
+
+	appendRecords(t, w, 1)
+	assert.Equal(t, uint64(headerSize+9), w.(*wal).unsynced)
+
+	appendRecords(t, w, 1)
+	assert.Equal(t, uint64(0), w.(*wal).unsynced)
+
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Sync_ByTime(t *testing.T) {
+	config := newTestConfig(t.TempDir())
+	config.SyncType = cfgs.FlushByTime
+	config.SyncDuration = 10 * time.Millisecond
+	w, _ := NewWAL(config, newTestPool(t))
+
+	appendRecords(t, w, 1)
+
+	assert.Eventually(t, func() bool {
+		w.(*wal).mu.Lock()
+		defer w.(*wal).mu.Unlock()
+		return w.(*wal).unsynced == 0
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	pool := newTestPool(t)
+
+	w, _ := NewWAL(newTestConfig(dir), pool)
+	appendRecords(t, w, 5)
+	assert.Nil(t, w.Close())
+
+	w, err := NewWAL(newTestConfig(dir), pool)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), w.NextLSN())
+
+	lsn, err := w.Append([]byte("record-05"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), lsn)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Reopen_DropsTornTail(t *testing.T) {
+	dir := t.TempDir()
+	pool := newTestPool(t)
+
+	w, _ := NewWAL(newTestConfig(dir), pool)
+	appendRecords(t, w, 2)
+	assert.Nil(t, w.Close())
+
+	// crash in the middle of appending the third record
+	path := segmentPath(dir, 1)
+	segment, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = segment.Write(encodeRecord(3, []byte("record-02"))[:headerSize+4])
+	_ = segment.Close()
+
+	w, err := NewWAL(newTestConfig(dir), pool)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), w.NextLSN())
+
+	appendRecords(t, w, 1)
+
+	rawData, _ := os.ReadFile(path)
+	count, end := scanRecords(rawData, 1)
+
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, int64(len(rawData)), end)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Truncate(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 8)
+
+	// segment of LSN 4 keeps LSN 5, so only the first segment is removed
+	assert.Nil(t, w.Truncate(5))
+
+	bases, _ := listSegments(dir)
+
+	assert.Equal(t, []uint64{4, 7}, bases)
+	assert.Equal(t, uint64(4), w.FirstLSN())
+
+	_, err := w.Replay(3)
+	assert.ErrorIs(t, err, ErrWALLSNIsTruncated)
+
+	// current segment is never removed
+	assert.Nil(t, w.Truncate(100))
+
+	bases, _ = listSegments(dir)
+
+	assert.Equal(t, []uint64{7}, bases)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Truncate_KeepsSegmentOfIterator(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 8)
+
+	it, _ := w.Replay(1)
+	assert.True(t, it.Next())
+
+	// the first segment is being read, so nothing is removed
+	assert.Nil(t, w.Truncate(5))
+
+	bases, _ := listSegments(dir)
+
+	assert.Equal(t, []uint64{1, 4, 7}, bases)
+
+	var lsns []uint64
+	for it.Next() {
+		lsns = append(lsns, it.Record().LSN)
+	}
+
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []uint64{2, 3, 4, 5, 6, 7, 8}, lsns)
+	assert.Nil(t, it.Close())
+
+	assert.Nil(t, w.Truncate(5))
+
+	bases, _ = listSegments(dir)
+
+	assert.Equal(t, []uint64{4, 7}, bases)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Truncate_SyncsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := NewWAL(newTestConfig(dir), newTestPool(t))
+	appendRecords(t, w, 4)
+
+	synced := 0
+	w.(*wal).openFileFunc = func(name string, flag int, perm os.FileMode) (file.File, error) {
+		if name == dir {
+			synced++
+		}
+		return openOSFile(name, flag, perm)
+	}
+
+	assert.Nil(t, w.Truncate(4))
+	assert.Equal(t, 1, synced)
+
+	// nothing is removed, so directory is not synced
+	assert.Nil(t, w.Truncate(4))
+	assert.Equal(t, 1, synced)
+	assert.Nil(t, w.Close())
+}
+
+func TestWAL_Truncate_Closed(t *testing.T) {
+	w, _ := NewWAL(newTestConfig(t.TempDir()), newTestPool(t))
+	assert.Nil(t, w.Close())
+
+	assert.ErrorIs(t, w.Truncate(1), ErrWALClosed)
+}
@@ -2,9 +2,14 @@
 package writer
 
 import (
+	"context"
 	"errors"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
+	"github.com/amirvalhalla/fspool/pkg/errs"
 	"github.com/amirvalhalla/fspool/pkg/file"
+	"github.com/amirvalhalla/fspool/pkg/logger"
 	"github.com/google/uuid"
+	"io"
 	"sync"
 )
 
@@ -13,20 +18,41 @@ var (
 	ErrFileWriterCouldNotWrite = errors.New("package writer - could not write data into file")
 	ErrFileWriterCouldNotClose = errors.New("package writer - could not close")
 	ErrFileWriterCouldNotSync  = errors.New("package writer - could not sync")
+	ErrFileWriterCouldNotStat  = errors.New("package writer - could not get file stat")
+	ErrFileWriterContextDone   = errors.New("package writer - writer has given up waiting for sync because context is done")
 )
 
 type fileWriter struct {
-	id    uuid.UUID
-	wFile file.File
-	rwMu  sync.RWMutex
+	id      uuid.UUID
+	wFile   file.File
+	ioMode  cfgs.IOMode
+	log     logger.Logger
+	pos     int64 // offset of file which next write continues from in PositionalIO mode (used by io.SeekCurrent)
+	rwMu    sync.RWMutex
+	syncMu  sync.Mutex
+	syncing bool       // true means an fsync is in flight
+	pending *syncBatch // Sync callers which have arrived while an fsync is in flight, they're covered by the next fsync
+}
+
+// syncBatch is a group of Sync callers which share one fsync
+type syncBatch struct {
+	done    chan struct{}
+	callers int
+	err     error
 }
 
 // FileWriter interface gives you some options for writing into a file
 type FileWriter interface {
 	// Write will write or update raw data into file
 	Write(rawData []byte, offset int64, seek int) error
-	// Sync will sync data from in-memory to disk
+	// Sync will sync data from in-memory to disk, concurrent callers share fsyncs and a returned Sync covers all writes issued before it
 	Sync() error
+	// SyncContext is Sync which gives up waiting for fsync when ctx is done, the fsync itself can't be canceled after it's started
+	SyncContext(ctx context.Context) error
+	// Size returns size of file
+	Size() (int64, error)
+	// Replace points writer to wFile and closes its previous file, it's used when file has been replaced by another one
+	Replace(wFile file.File) error
 	// GetId return id of FileWriter
 	GetId() uuid.UUID
 	// Close func provides close writer instance
@@ -34,11 +60,14 @@ type FileWriter interface {
 }
 
 // NewFileWriter func provides new instance of FileWriter interface with unique memory addresses of its objects
-func NewFileWriter(file file.File) (FileWriter, uuid.UUID) {
+// ioMode defines whether writer uses WriteAt or Seek followed by Write, failures are returned to caller without logging and syncs are reported to log (nil means no logging)
+func NewFileWriter(file file.File, ioMode cfgs.IOMode, log logger.Logger) (FileWriter, uuid.UUID) {
 	id := uuid.New()
 	return &fileWriter{
-		id:    id,
-		wFile: file,
+		id:     id,
+		wFile:  file,
+		ioMode: ioMode,
+		log:    logger.OrNop(log),
 	}, id
 }
 
@@ -47,24 +76,82 @@ func (w *fileWriter) Write(rawData []byte, offset int64, seek int) error {
 	w.rwMu.Lock()
 	defer w.rwMu.Unlock()
 
-	if _, err := w.wFile.Seek(offset, seek); err != nil {
-		return ErrFileWriterCouldNotSeek
+	if w.ioMode == cfgs.PositionalIO {
+		return w.writeAt(rawData, offset, seek)
+	}
+
+	pos, err := w.wFile.Seek(offset, seek)
+	if err != nil {
+		return errs.New("seek", "", offset, ErrFileWriterCouldNotSeek, err)
 	}
 
 	if _, err := w.wFile.Write(rawData); err != nil {
-		return ErrFileWriterCouldNotWrite
+		return errs.New("write", "", pos, ErrFileWriterCouldNotWrite, err)
 	}
 
 	return nil
 }
 
-// Sync will sync data from in-memory to disk
+// Sync will sync data from in-memory to disk, concurrent callers share fsyncs and a returned Sync covers all writes issued before it
+// an fsync which is in flight may have started before writes of caller, so callers which arrive meanwhile are batched into the next fsync
 func (w *fileWriter) Sync() error {
+	return w.SyncContext(context.Background())
+}
+
+// SyncContext is Sync which gives up waiting for fsync when ctx is done, the fsync itself can't be canceled after it's started
+// a caller which gives up still leaves its batch committed, so later callers are covered by it as usual
+func (w *fileWriter) SyncContext(ctx context.Context) error {
+	w.syncMu.Lock()
+
+	if w.syncing {
+		if w.pending == nil {
+			w.pending = &syncBatch{done: make(chan struct{})}
+		}
+		b := w.pending
+		b.callers++
+		w.syncMu.Unlock()
+
+		return w.wait(ctx, b)
+	}
+
+	w.syncing = true
+	w.syncMu.Unlock()
+
+	b := &syncBatch{done: make(chan struct{}), callers: 1}
+	if ctx.Done() == nil {
+		w.commit(b)
+		return b.err
+	}
+
+	go w.commit(b)
+
+	return w.wait(ctx, b)
+}
+
+// Size returns size of file
+func (w *fileWriter) Size() (int64, error) {
+	w.rwMu.RLock()
+	defer w.rwMu.RUnlock()
+
+	fInfo, err := w.wFile.Stat()
+	if err != nil {
+		return 0, errs.New("stat", "", errs.NoOffset, ErrFileWriterCouldNotStat, err)
+	}
+
+	return fInfo.Size(), nil
+}
+
+// Replace points writer to wFile and closes its previous file, offset of writer is reset to the beginning of wFile
+func (w *fileWriter) Replace(wFile file.File) error {
 	w.rwMu.Lock()
 	defer w.rwMu.Unlock()
 
-	if err := w.wFile.Sync(); err != nil {
-		return ErrFileWriterCouldNotSync
+	prev := w.wFile
+	w.wFile = wFile
+	w.pos = 0
+
+	if err := prev.Close(); err != nil {
+		return errs.New("close", "", errs.NoOffset, ErrFileWriterCouldNotClose, err)
 	}
 
 	return nil
@@ -81,8 +168,85 @@ func (w *fileWriter) Close() error {
 	defer w.rwMu.Unlock()
 
 	if err := w.wFile.Close(); err != nil {
-		return ErrFileWriterCouldNotClose
+		return errs.New("close", "", errs.NoOffset, ErrFileWriterCouldNotClose, err)
+	}
+
+	return nil
+}
+
+// commit runs fsync of batch and releases its callers, then it commits the next batch if any caller has arrived meanwhile
+func (w *fileWriter) commit(b *syncBatch) {
+	b.err = w.fsync()
+	close(b.done)
+
+	if b.err == nil {
+		w.log.Debug("package writer - synced file", "writer", w.id, "callers", b.callers)
+	}
+
+	w.syncMu.Lock()
+	next := w.pending
+	w.pending = nil
+
+	if next == nil {
+		w.syncing = false
+		w.syncMu.Unlock()
+		return
+	}
+	w.syncMu.Unlock()
+
+	go w.commit(next)
+}
+
+// wait waits for b to be committed or ctx to be done
+func (w *fileWriter) wait(ctx context.Context, b *syncBatch) error {
+	select {
+	case <-b.done:
+		return b.err
+	case <-ctx.Done():
+		return errs.New("sync", "", errs.NoOffset, ErrFileWriterContextDone, ctx.Err())
 	}
+}
+
+// fsync syncs data of file from in-memory to disk
+func (w *fileWriter) fsync() error {
+	w.rwMu.RLock()
+	defer w.rwMu.RUnlock()
+
+	if err := w.wFile.Sync(); err != nil {
+		return errs.New("sync", "", errs.NoOffset, ErrFileWriterCouldNotSync, err)
+	}
+
+	return nil
+}
+
+// writeAt writes data by WriteAt without touching offset of file (caller must hold w.rwMu)
+func (w *fileWriter) writeAt(rawData []byte, offset int64, seek int) error {
+	var pos int64
+
+	switch seek {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = w.pos + offset
+	case io.SeekEnd:
+		fInfo, err := w.wFile.Stat()
+		if err != nil {
+			return errs.New("seek", "", offset, ErrFileWriterCouldNotSeek, err)
+		}
+		pos = fInfo.Size() + offset
+	default:
+		return ErrFileWriterCouldNotSeek
+	}
+
+	if pos < 0 {
+		return ErrFileWriterCouldNotSeek
+	}
+
+	if _, err := w.wFile.WriteAt(rawData, pos); err != nil {
+		return errs.New("write", "", pos, ErrFileWriterCouldNotWrite, err)
+	}
+
+	w.pos = pos + int64(len(rawData))
 
 	return nil
 }
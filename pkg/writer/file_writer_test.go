@@ -1,12 +1,17 @@
 package writer
 
 import (
+	"context"
 	mockfile "github.com/amirvalhalla/fspool/mocks/file"
+	"github.com/amirvalhalla/fspool/pkg/cfgs"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"io/fs"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestNewFileWriter(t *testing.T) {
@@ -14,7 +19,7 @@ func TestNewFileWriter(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	assert.NotNil(t, fWriter)
 }
@@ -24,7 +29,7 @@ func TestFileWriter_Write(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), nil).Times(1)
 	mockFile.EXPECT().Write([]byte{}).Return(0, nil).Times(1)
@@ -39,13 +44,13 @@ func TestFileWriter_Write_CouldNotSeek(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), ErrFileWriterCouldNotSeek).Times(1)
 
 	err := fWriter.Write([]byte{}, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFileWriterCouldNotSeek.Error())
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotSeek)
 }
 
 func TestFileWriter_Write_CouldNotWrite(t *testing.T) {
@@ -53,14 +58,14 @@ func TestFileWriter_Write_CouldNotWrite(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	mockFile.EXPECT().Seek(int64(0), 0).Return(int64(0), nil).Times(1)
 	mockFile.EXPECT().Write([]byte{}).Return(0, ErrFileWriterCouldNotWrite).Times(1)
 
 	err := fWriter.Write([]byte{}, 0, io.SeekStart)
 
-	assert.EqualError(t, err, ErrFileWriterCouldNotWrite.Error())
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotWrite)
 }
 
 func TestFileReader_GetId(t *testing.T) {
@@ -68,7 +73,7 @@ func TestFileReader_GetId(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	id := fWriter.GetId()
 
@@ -83,7 +88,7 @@ func TestFileWriter_Sync(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFile.EXPECT().Sync().Return(nil).Times(1)
 
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	err := fWriter.Sync()
 
@@ -97,11 +102,11 @@ func TestFileWriter_Sync_CouldNotSync(t *testing.T) {
 	mockFile := mockfile.NewMockFile(mockCtrl)
 	mockFile.EXPECT().Sync().Return(ErrFileWriterCouldNotSync).Times(1)
 
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	err := fWriter.Sync()
 
-	assert.EqualError(t, err, ErrFileWriterCouldNotSync.Error())
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotSync)
 }
 
 func TestFileWriter_Close(t *testing.T) {
@@ -109,7 +114,7 @@ func TestFileWriter_Close(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	mockFile.EXPECT().Close().Return(nil).Times(1)
 
@@ -123,11 +128,333 @@ func TestFileWriter_Close_CouldNotClose(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockFile := mockfile.NewMockFile(mockCtrl)
-	fWriter, _ := NewFileWriter(mockFile)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
 
 	mockFile.EXPECT().Close().Return(ErrFileWriterCouldNotClose).Times(1)
 
 	err := fWriter.Close()
 
-	assert.EqualError(t, err, ErrFileWriterCouldNotClose.Error())
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotClose)
+}
+
+func TestFileWriter_Replace(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	newMockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	mockFile.EXPECT().WriteAt([]byte{1, 2}, int64(0)).Return(2, nil).Times(1)
+	mockFile.EXPECT().Close().Return(nil).Times(1)
+
+	// writes after replacing go to the new file and they continue from its beginning
+	newMockFile.EXPECT().WriteAt([]byte{3}, int64(0)).Return(1, nil).Times(1)
+
+	assert.Nil(t, fWriter.Write([]byte{1, 2}, 0, io.SeekStart))
+	assert.Nil(t, fWriter.Replace(newMockFile))
+	assert.Nil(t, fWriter.Write([]byte{3}, 0, io.SeekCurrent))
+}
+
+func TestFileWriter_Replace_CouldNotClose(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	newMockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
+
+	mockFile.EXPECT().Close().Return(syscall.EIO).Times(1)
+	newMockFile.EXPECT().Close().Return(nil).Times(1)
+
+	err := fWriter.Replace(newMockFile)
+
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotClose)
+	assert.ErrorIs(t, err, syscall.EIO)
+
+	// writer keeps the new file even if closing the previous one fails
+	assert.Nil(t, fWriter.Close())
+}
+
+func TestFileWriter_Size(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(10)).Times(1)
+
+	size, err := fWriter.Size()
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), size)
+}
+
+func TestFileWriter_Size_CouldNotStat(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.SeekIO, nil)
+
+	mockFile.EXPECT().Stat().Return(nil, ErrFileWriterCouldNotStat).Times(1)
+
+	_, err := fWriter.Size()
+
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotStat)
+}
+
+func TestFileWriter_Write_PositionalIO(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	gomock.InOrder(
+		mockFile.EXPECT().WriteAt([]byte{1, 2}, int64(4)).Return(2, nil).Times(1),
+		mockFile.EXPECT().WriteAt([]byte{3}, int64(6)).Return(1, nil).Times(1),
+	)
+
+	assert.Nil(t, fWriter.Write([]byte{1, 2}, 4, io.SeekStart))
+
+	// io.SeekCurrent continues from the end of previous write
+	assert.Nil(t, fWriter.Write([]byte{3}, 0, io.SeekCurrent))
+}
+
+func TestFileWriter_Write_PositionalIO_SeekEnd(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	mockFileInfo := mockfile.NewMockFileInfo(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	mockFile.EXPECT().Stat().Return(mockFileInfo, nil).Times(1)
+	mockFileInfo.EXPECT().Size().Return(int64(10)).Times(1)
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(10)).Return(1, nil).Times(1)
+
+	err := fWriter.Write([]byte{1}, 0, io.SeekEnd)
+
+	assert.Nil(t, err)
+}
+
+func TestFileWriter_Write_PositionalIO_CouldNotSeek(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	err := fWriter.Write([]byte{1}, -1, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotSeek)
+}
+
+func TestFileWriter_Write_PositionalIO_CouldNotWrite(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(0)).Return(0, ErrFileWriterCouldNotWrite).Times(1)
+
+	err := fWriter.Write([]byte{1}, 0, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotWrite)
+}
+
+func TestFileWriter_Write_CouldNotWrite_KeepsCause(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	cause := &fs.PathError{Op: "write", Path: "/test/test.txt", Err: syscall.ENOSPC}
+	mockFile.EXPECT().WriteAt([]byte{1}, int64(4)).Return(0, cause).Times(1)
+
+	err := fWriter.Write([]byte{1}, 4, io.SeekStart)
+
+	assert.ErrorIs(t, err, ErrFileWriterCouldNotWrite)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.NotErrorIs(t, err, syscall.EBADF)
+}
+
+func pendingSyncs(fWriter FileWriter) int {
+	w := fWriter.(*fileWriter)
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	if w.pending == nil {
+		return 0
+	}
+
+	return w.pending.callers
+}
+
+func TestFileWriter_Sync_GroupCommit(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	inFlight := make(chan struct{})
+	unblock := make(chan struct{})
+
+	// the first fsync is in flight while the others arrive, so they share the second one
+	gomock.InOrder(
+		mockFile.EXPECT().Sync().DoAndReturn(func() error {
+			close(inFlight)
+			<-unblock
+			return nil
+		}).Times(1),
+		mockFile.EXPECT().Sync().Return(nil).Times(1),
+	)
+
+	synced := make(chan error, 11)
+	go func() {
+		synced <- fWriter.Sync()
+	}()
+
+	<-inFlight
+
+	for i := 0; i < 10; i++ {
+		go func() {
+			synced <- fWriter.Sync()
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return pendingSyncs(fWriter) == 10 }, time.Second, time.Millisecond)
+	close(unblock)
+
+	for i := 0; i < 11; i++ {
+		assert.Nil(t, <-synced)
+	}
+}
+
+func TestFileWriter_Sync_GroupCommit_CouldNotSync(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	inFlight := make(chan struct{})
+	unblock := make(chan struct{})
+
+	gomock.InOrder(
+		mockFile.EXPECT().Sync().DoAndReturn(func() error {
+			close(inFlight)
+			<-unblock
+			return nil
+		}).Times(1),
+		mockFile.EXPECT().Sync().Return(syscall.EIO).Times(1),
+		mockFile.EXPECT().Sync().Return(nil).Times(1),
+	)
+
+	first := make(chan error)
+	go func() {
+		first <- fWriter.Sync()
+	}()
+
+	<-inFlight
+
+	batched := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			batched <- fWriter.Sync()
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return pendingSyncs(fWriter) == 2 }, time.Second, time.Millisecond)
+	close(unblock)
+
+	assert.Nil(t, <-first)
+
+	// all callers of a batch get error of its fsync
+	for i := 0; i < 2; i++ {
+		err := <-batched
+		assert.ErrorIs(t, err, ErrFileWriterCouldNotSync)
+		assert.ErrorIs(t, err, syscall.EIO)
+	}
+
+	// a new fsync is issued once batches are done
+	assert.Nil(t, fWriter.Sync())
+}
+
+func TestFileWriter_SyncContext_ContextDone(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	inFlight := make(chan struct{})
+	unblock := make(chan struct{})
+	committed := make(chan struct{})
+
+	// batch of the caller which gives up is still committed
+	gomock.InOrder(
+		mockFile.EXPECT().Sync().DoAndReturn(func() error {
+			close(inFlight)
+			<-unblock
+			return nil
+		}).Times(1),
+		mockFile.EXPECT().Sync().DoAndReturn(func() error {
+			close(committed)
+			return nil
+		}).Times(1),
+	)
+
+	first := make(chan error)
+	go func() {
+		first <- fWriter.Sync()
+	}()
+
+	<-inFlight
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := fWriter.SyncContext(ctx)
+
+	assert.ErrorIs(t, err, ErrFileWriterContextDone)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(unblock)
+	assert.Nil(t, <-first)
+	<-committed
+}
+
+func TestFileWriter_SyncContext_Leader_ContextDone(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFile := mockfile.NewMockFile(mockCtrl)
+	fWriter, _ := NewFileWriter(mockFile, cfgs.PositionalIO, nil)
+
+	unblock := make(chan struct{})
+	mockFile.EXPECT().Sync().DoAndReturn(func() error {
+		<-unblock
+		return nil
+	}).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// caller which has started the fsync gives up too, fsync goes on without it
+	err := fWriter.SyncContext(ctx)
+
+	assert.ErrorIs(t, err, ErrFileWriterContextDone)
+	close(unblock)
+
+	// a new caller is covered by a new fsync whether the previous one is still in flight or not
+	mockFile.EXPECT().Sync().Return(nil).Times(1)
+	assert.Nil(t, fWriter.Sync())
 }